@@ -0,0 +1,174 @@
+// Command cachereplay replays the recorded access logs against the eviction policies and compares their hit
+// ratios: cachereplay -size 512M -policies lru,arc access.log.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/accesslog"
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/bytesize"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+)
+
+type request struct {
+	key  string
+	size uint64
+}
+
+func (r request) Size() uint64 {
+	return r.size
+}
+
+type result struct {
+	policy                string
+	requests, hits        int
+	totalBytes, hitsBytes uint64
+}
+
+func main() {
+	size := flag.String("size", "1G", "Size of the cache, e.g. 512M")
+	policies := flag.String("policies", "lru,lfu,2q,arc", "Eviction policies to compare")
+	route := flag.String("route", "/fill/", "Only the successful GET requests of the route are replayed")
+	flag.Parse()
+
+	trace, err := readTrace(flag.Args(), *route)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "policy\trequests\thits\thit ratio\tbyte hit ratio")
+
+	for _, policy := range strings.Split(*policies, ",") {
+		res, err := replay(strings.TrimSpace(policy), bytesize.Parse(*size), trace)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		_, _ = fmt.Fprintf(writer, "%s\t%d\t%d\t%.4f\t%.4f\n", res.policy, res.requests, res.hits,
+			ratio(uint64(res.hits), uint64(res.requests)), ratio(res.hitsBytes, res.totalBytes))
+	}
+
+	_ = writer.Flush()
+}
+
+// errNoEntries none of the lines is an access log entry, e.g. the format is not supported.
+var errNoEntries = errors.New("no access log entries")
+
+// readTrace the files are read in order, no files means the standard input.
+func readTrace(files []string, route string) ([]request, error) {
+	if len(files) == 0 {
+		return read(os.Stdin, "stdin", route, nil)
+	}
+
+	var trace []request
+	for _, name := range files {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		trace, err = read(file, name, route, trace)
+		_ = file.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return trace, nil
+}
+
+// read the rejected lines are reported, a file without a single entry is an error.
+func read(reader io.Reader, name string, route string, trace []request) ([]request, error) {
+	trace, entries, rejected, err := scan(reader, route, trace)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if entries == 0 {
+		return nil, fmt.Errorf("%s: %w, %d lines rejected", name, errNoEntries, rejected)
+	}
+
+	if rejected > 0 {
+		log.Printf("%s: %d lines rejected, %d entries read", name, rejected, entries)
+	}
+
+	return trace, nil
+}
+
+// scan counts the entries and the rejected lines, only the matching entries are added to the trace.
+func scan(reader io.Reader, route string, trace []request) ([]request, int, int, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var entries, rejected int
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		entry, err := accesslog.Parse(scanner.Text())
+		if err != nil {
+			rejected++
+			continue
+		}
+
+		entries++
+		if entry.Method != "GET" || entry.Status != 200 || !strings.HasPrefix(entry.URI, route) {
+			continue
+		}
+
+		size := uint64(1)
+		if entry.Bytes > 0 {
+			size = uint64(entry.Bytes)
+		}
+
+		trace = append(trace, request{key: entry.URI, size: size})
+	}
+
+	return trace, entries, rejected, scanner.Err()
+}
+
+// replay a miss puts the preview into the cache, as the service does.
+func replay(policy string, size uint64, trace []request) (result, error) {
+	cache, err := lru.NewPolicy(policy, policy, size, bus.NewSyncBus())
+	if err != nil {
+		return result{}, err
+	}
+
+	res := result{policy: policy, requests: len(trace)}
+	for _, r := range trace {
+		res.totalBytes += r.size
+
+		if _, ok := cache.Get(r.key); ok {
+			res.hits++
+			res.hitsBytes += r.size
+
+			continue
+		}
+
+		cache.Put(r.key, r)
+	}
+
+	return res, nil
+}
+
+func ratio(a, b uint64) float64 {
+	if b == 0 {
+		return 0
+	}
+
+	return float64(a) / float64(b)
+}
@@ -49,15 +49,20 @@ func main() {
 		return
 	}
 
-	app := imgprev.New(config)
+	app, err := imgprev.New(config)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
 	httpServ := server.New(app)
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 	defer cancel()
 
-	app.Logger().Info("http server starting")
+	app.Logger().Info("http server starting", "addr", config.Server.Addr)
 	if err := httpServ.ListenAndServe(ctx); err != nil {
-		app.Logger().Error(err.Error())
+		app.Logger().Error("http server stopped", "error", err)
 	}
 }
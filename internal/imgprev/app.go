@@ -1,20 +1,33 @@
 package imgprev
 
 import (
+	"fmt"
 	"os"
 	"time"
 
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
 	"github.com/rez1dent3/otus-final/internal/pkg/bus"
 	"github.com/rez1dent3/otus-final/internal/pkg/bytesize"
 	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
 	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/headerpolicy"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
 	"github.com/rez1dent3/otus-final/internal/pkg/logger"
 	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
 	"github.com/rez1dent3/otus-final/internal/pkg/transformer"
 	"github.com/rez1dent3/otus-final/internal/transport"
 )
 
+const (
+	DefaultMaxRedirects = 5
+	DefaultTotalTimeout = 10 * time.Second
+)
+
 var supportedContentTypes = []string{
 	"image/jpeg",
 	"image/png",
@@ -25,29 +38,135 @@ type AppInterface interface {
 	Transform() transformer.TransformInterface
 	Fetcher() fetcher.FetchInterface
 	Logger() logger.LogInterface
+	Metrics() metrics.RegistryInterface
+	Breaker() breaker.BreakerInterface
+	PreviewCache() lru.CacheInterface
 	Config() *Config
 	Purge()
+	Close()
 }
 
 type Config struct {
 	Server struct {
 		Addr string
+
+		// TrustRequestID accept X-Request-ID from the client (e.g. set by a balancer) instead of generating.
+		TrustRequestID bool `yaml:"trustRequestId"`
+		// TrustForwardedFor the client address is the last one of X-Forwarded-For appended by the balancer,
+		// otherwise the address of the connection.
+		TrustForwardedFor bool `yaml:"trustForwardedFor"`
 	}
 
 	Logger struct {
-		Level string
+		Level  string
+		Format string
+
+		AccessLog struct {
+			// Format text (default), json or combined.
+			Format string
+			// SampleRate the share of successful requests to log, 0 logs all of them.
+			SampleRate float64 `yaml:"sampleRate"`
+		} `yaml:"accessLog"`
+	}
+
+	Source struct {
+		// AllowedHosts if not empty, only these hosts can be fetched. DeniedHosts are never fetched.
+		// A pattern is a host, a wildcard subdomain (*.example.com) or a network (10.0.0.0/8).
+		AllowedHosts []string `yaml:"allowedHosts"`
+		DeniedHosts  []string `yaml:"deniedHosts"`
+
+		// MetricHosts the patterns reported in the host label of the metrics, any other host is "other".
+		// Empty means AllowedHosts.
+		MetricHosts []string `yaml:"metricHosts"`
+
+		// BlockedNetworks are never connected to, even through dns or redirects. Empty means the private,
+		// loopback and link-local networks. AllowedNetworks are the exceptions, e.g. the docker network.
+		BlockedNetworks []string `yaml:"blockedNetworks"`
+		AllowedNetworks []string `yaml:"allowedNetworks"`
+
+		// StripQuery drops the query of the /fill request, by default the http sources pass it to the origin.
+		StripQuery bool `yaml:"stripQuery"`
+
+		// MaxRedirects 0 means DefaultMaxRedirects, a negative value disables redirects.
+		MaxRedirects int `yaml:"maxRedirects"`
+		// ForbidDowngrade do not follow redirects from https to http.
+		ForbidDowngrade bool `yaml:"forbidDowngrade"`
+
+		// MaxSize the limit of the original image (e.g. 20M), empty means no limit.
+		MaxSize string `yaml:"maxSize"`
+
+		// Backends the sources of the originals, chosen by the longest matching prefix of the source url.
+		// Empty means a single http backend for every url.
+		Backends []Backend
+
+		// StrictContentType the declared Content-Type must match the content, otherwise only the content is checked.
+		StrictContentType bool `yaml:"strictContentType"`
+
+		// Timeouts of the request phases, Total limits the whole fetch including retries (DefaultTotalTimeout).
+		Timeouts struct {
+			Dial    time.Duration
+			TLS     time.Duration `yaml:"tls"`
+			Headers time.Duration
+			Total   time.Duration
+		}
+
+		// Retries of connection failures and 502, 503, 504 responses, the delay doubles up to MaxBackoff.
+		Retries struct {
+			Max        int
+			Backoff    time.Duration
+			MaxBackoff time.Duration `yaml:"maxBackoff"`
+		}
+
+		// Limits per host: MaxConcurrent requests in flight and Rate requests per second with Burst,
+		// at most MaxQueue requests wait for QueueTimeout. Zero MaxConcurrent and Rate disable the limits.
+		Limits struct {
+			MaxConcurrent int `yaml:"maxConcurrent"`
+			Rate          float64
+			Burst         int
+			MaxQueue      int           `yaml:"maxQueue"`
+			QueueTimeout  time.Duration `yaml:"queueTimeout"`
+		}
+
+		// Headers of the client forwarded to the origin, the hop-by-hop ones never are, the credentials only by a rule.
+		Headers headerpolicy.Config
+
+		// Schemes of the source urls without one: the scheme of the first matching rule or Default,
+		// https if empty. The url keeps its own scheme: https://host/path or /fill/300/200/https/host/path.
+		Schemes struct {
+			Default string
+			Hosts   []SchemeRule
+		}
+
+		// Credentials sent to the matching hosts: Username and Password (basic), Token (bearer)
+		// and the client certificate from the Cert and Key PEM files.
+		Credentials []Credential
+
+		// Breaker fails fast with 503 after Threshold consecutive failures of a host for CoolDown,
+		// then Probes successful requests close it. A negative threshold disables the breaker.
+		Breaker struct {
+			Threshold int
+			CoolDown  time.Duration `yaml:"coolDown"`
+			Probes    int
+		}
 	}
 
 	Original struct {
 		CacheDir    string `yaml:"cacheDir"`
 		CachePrefix string `yaml:"cachePrefix"`
 		CacheSize   string `yaml:"cacheSize"`
+
+		CacheOptions `yaml:",inline"`
 	}
 
 	Preview struct {
 		CacheDir    string `yaml:"cacheDir"`
 		CachePrefix string `yaml:"cachePrefix"`
 		CacheSize   string `yaml:"cacheSize"`
+
+		CacheOptions `yaml:",inline"`
+
+		// MemorySize the limit of the in-memory hot tier in front of the disk cache, empty or off disables it.
+		MemorySize string `yaml:"memorySize"`
 	}
 }
 
@@ -55,40 +174,160 @@ type impl struct {
 	fetch      fetcher.FetchInterface
 	commandBus bus.CommandBusInterface
 	log        logger.LogInterface
+	registry   metrics.RegistryInterface
+	breaker    breaker.BreakerInterface
 	config     *Config
 
 	transform transformer.TransformInterface
 
 	fetcherCache lru.CacheInterface
+	previewCache lru.CacheInterface
+
+	// janitors stop the removal of the expired cache entries.
+	janitors []func()
 }
 
-func New(config *Config) AppInterface {
+func New(config *Config) (AppInterface, error) {
+	hosts, err := hostmatch.NewPolicy(config.Source.AllowedHosts, config.Source.DeniedHosts)
+	if err != nil {
+		return nil, fmt.Errorf("source hosts: %w", err)
+	}
+
+	// netguard blocks the private networks for nil but nothing for an empty list, as "blockedNetworks: []" is decoded
+	blocked := config.Source.BlockedNetworks
+	if len(blocked) == 0 {
+		blocked = nil
+	}
+
+	metricHosts := config.Source.MetricHosts
+	if len(metricHosts) == 0 {
+		metricHosts = config.Source.AllowedHosts
+	}
+
+	hostLabels, err := hostmatch.NewLabels(metricHosts)
+	if err != nil {
+		return nil, fmt.Errorf("source metric hosts: %w", err)
+	}
+
+	guard, err := netguard.New(blocked, config.Source.AllowedNetworks)
+	if err != nil {
+		return nil, fmt.Errorf("source networks: %w", err)
+	}
+
+	headers, err := headerpolicy.New(config.Source.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("source headers: %w", err)
+	}
+
+	schemes, err := newSchemes(config.Source.Schemes.Default, config.Source.Schemes.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("source schemes: %w", err)
+	}
+
+	credentials, err := newCredentials(config.Source.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("source credentials: %w", err)
+	}
+
+	maxSize := int64(bytesize.Parse(config.Source.MaxSize))
+
 	hash := hsum.New()
 	commandBus := bus.NewSyncBus()
-	log := logger.New(config.Logger.Level, os.Stdout)
+	log := logger.NewFormat(config.Logger.Format, config.Logger.Level, os.Stdout)
+	registry := metrics.New()
+	subscribeCacheMetrics(commandBus, registry)
+
+	var limiter throttle.LimiterInterface
+	if limits := config.Source.Limits; limits.MaxConcurrent > 0 || limits.Rate > 0 {
+		limiter = throttle.New(throttle.Options{
+			MaxConcurrent: limits.MaxConcurrent,
+			Rate:          limits.Rate,
+			Burst:         limits.Burst,
+			MaxQueue:      limits.MaxQueue,
+			QueueTimeout:  limits.QueueTimeout,
+		})
+	}
+
+	var hostBreaker breaker.BreakerInterface
+	if config.Source.Breaker.Threshold >= 0 {
+		hostBreaker = breaker.New(breaker.Options{
+			Threshold: config.Source.Breaker.Threshold,
+			CoolDown:  config.Source.Breaker.CoolDown,
+			Probes:    config.Source.Breaker.Probes,
+			IsFailure: transport.IsFailure,
+		}, log)
+	}
 
 	// fetcher
 	fm := fs.New(config.Original.CacheDir, config.Original.CachePrefix)
-	fetcherCache := lru.New(bytesize.Parse(config.Original.CacheSize), commandBus)
-	fetcherTransport := transport.New(hash, fetcherCache, fm, log)
+	fetcherCache, err := NewCache(CacheOriginal, config.Original.CacheSize, config.Original.CacheOptions, commandBus)
+	if err != nil {
+		return nil, err
+	}
+
+	previewCache, err := NewCache(CachePreview, config.Preview.CacheSize, config.Preview.CacheOptions, commandBus)
+	if err != nil {
+		return nil, err
+	}
+
+	fetcherTransport := transport.New(hash, fetcherCache, fm, log, registry, transport.Options{
+		Guard:           guard,
+		Hosts:           hosts,
+		MaxRedirects:    maxRedirects(config.Source.MaxRedirects),
+		ForbidDowngrade: config.Source.ForbidDowngrade,
+		MaxSize:         maxSize,
+
+		DialTimeout:           config.Source.Timeouts.Dial,
+		TLSHandshakeTimeout:   config.Source.Timeouts.TLS,
+		ResponseHeaderTimeout: config.Source.Timeouts.Headers,
+
+		Retries:         config.Source.Retries.Max,
+		RetryBackoff:    config.Source.Retries.Backoff,
+		RetryMaxBackoff: config.Source.Retries.MaxBackoff,
+
+		Credentials: credentials,
+		Limiter:     limiter,
+		Breaker:     hostBreaker,
+		HostLabels:  hostLabels,
+	})
 
 	// cleanup original images
 	commandBus.Subscribe(lru.EventEvict, func(input any) {
-		if val, ok := input.(transport.ResponseItem); ok {
+		if val, ok := input.(transport.ResponseItem); ok && val.HasContent() {
 			if err := fm.Delete(hash.HashByString(val.URL)); err != nil {
-				log.Error(err.Error())
+				log.Error("failed to delete original", "url", val.URL, "error", err)
 			}
 		}
 	})
 
+	timeout := totalTimeout(config.Source.Timeouts.Total)
+	content := sniff.New(supportedContentTypes, config.Source.StrictContentType)
+	fetch := fetcher.NewHTTPFetcher(fetcherTransport, timeout, content, hosts, maxSize, headers, schemes)
+	fetch, err = newRouter(config.Source.Backends, backendContext{
+		config:  config,
+		http:    fetch,
+		content: content,
+		maxSize: maxSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &impl{
-		fetch:        fetcher.NewHTTPFetcher(fetcherTransport, time.Second, supportedContentTypes),
+		fetch:        fetch,
 		commandBus:   commandBus,
 		log:          log,
+		registry:     registry,
+		breaker:      hostBreaker,
 		config:       config,
 		fetcherCache: fetcherCache,
-		transform:    transformer.NewStack(),
-	}
+		previewCache: previewCache,
+		transform:    newTransform(registry),
+		janitors: []func(){
+			startJanitor(fetcherCache, config.Original.TTL),
+			startJanitor(previewCache, config.Preview.TTL),
+		},
+	}, nil
 }
 
 func (i *impl) Fetcher() fetcher.FetchInterface {
@@ -107,6 +346,20 @@ func (i *impl) Logger() logger.LogInterface {
 	return i.log
 }
 
+func (i *impl) Metrics() metrics.RegistryInterface {
+	return i.registry
+}
+
+// Breaker nil if disabled.
+func (i *impl) Breaker() breaker.BreakerInterface {
+	return i.breaker
+}
+
+// PreviewCache the index of the preview files.
+func (i *impl) PreviewCache() lru.CacheInterface {
+	return i.previewCache
+}
+
 func (i *impl) Config() *Config {
 	return i.config
 }
@@ -114,3 +367,29 @@ func (i *impl) Config() *Config {
 func (i *impl) Purge() {
 	i.fetcherCache.Purge()
 }
+
+// Close stops the background work of the caches.
+func (i *impl) Close() {
+	for _, stop := range i.janitors {
+		stop()
+	}
+}
+
+func maxRedirects(value int) int {
+	switch {
+	case value == 0:
+		return DefaultMaxRedirects
+	case value < 0:
+		return 0
+	default:
+		return value
+	}
+}
+
+func totalTimeout(value time.Duration) time.Duration {
+	if value <= 0 {
+		return DefaultTotalTimeout
+	}
+
+	return value
+}
@@ -0,0 +1,70 @@
+package imgprev
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/bytesize"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+)
+
+// CacheOptions how the index of a cache is kept in memory.
+type CacheOptions struct {
+	// Policy of eviction: lru (default), lfu, 2q or arc. 2q and arc resist the sweeps through the catalogue.
+	Policy string
+
+	// Shards splits the lru index into independently locked parts sharing the size limit, 0 keeps a single lock.
+	Shards int
+
+	// TTL of the lru entries, 0 keeps them until they are evicted. The expired ones are removed in the background.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// JanitorInterval the expired entries are removed at least this often.
+const JanitorInterval = time.Minute
+
+var ErrCacheOptions = errors.New("cache option is supported by the lru policy only")
+
+// NewCache the index of the named cache limited by size, e.g. 512M.
+func NewCache(
+	name string,
+	size string,
+	options CacheOptions,
+	commandBus bus.CommandBusInterface,
+) (lru.CacheInterface, error) {
+	if policy := strings.ToLower(options.Policy); policy == "" || policy == lru.PolicyLRU {
+		return lru.NewWithOptions(name, bytesize.Parse(size), commandBus, lru.Options{
+			Shards: options.Shards,
+			TTL:    options.TTL,
+		}), nil
+	}
+
+	if options.Shards > 0 || options.TTL > 0 {
+		return nil, fmt.Errorf("cache %s, %s: %w", name, options.Policy, ErrCacheOptions)
+	}
+
+	cache, err := lru.NewPolicy(options.Policy, name, bytesize.Parse(size), commandBus)
+	if err != nil {
+		return nil, fmt.Errorf("cache %s: %w", name, err)
+	}
+
+	return cache, nil
+}
+
+// startJanitor returns the stop, a no-op if the entries of the cache never expire.
+func startJanitor(cache lru.CacheInterface, ttl time.Duration) func() {
+	expirer, ok := cache.(lru.ExpirerInterface)
+	if !ok || ttl <= 0 {
+		return func() {}
+	}
+
+	interval := JanitorInterval
+	if ttl < interval {
+		interval = ttl
+	}
+
+	return lru.StartJanitor(expirer, interval)
+}
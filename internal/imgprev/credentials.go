@@ -0,0 +1,52 @@
+package imgprev
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/rez1dent3/otus-final/internal/transport"
+)
+
+type Credential struct {
+	Hosts []string
+
+	Username string
+	Password string
+	Token    string
+
+	Cert string
+	Key  string
+}
+
+func newCredentials(config []Credential) ([]transport.Credential, error) {
+	result := make([]transport.Credential, 0, len(config))
+	for _, c := range config {
+		hosts, err := hostmatch.New(c.Hosts)
+		if err != nil {
+			return nil, err
+		}
+
+		item := transport.Credential{Hosts: hosts}
+
+		switch {
+		case c.Token != "":
+			item.Authorization = transport.BearerAuth(c.Token)
+		case c.Username != "":
+			item.Authorization = transport.BasicAuth(c.Username, c.Password)
+		}
+
+		if c.Cert != "" {
+			certificate, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+			if err != nil {
+				return nil, fmt.Errorf("client certificate: %w", err)
+			}
+
+			item.Certificate = &certificate
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
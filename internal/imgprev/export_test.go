@@ -0,0 +1,15 @@
+package imgprev
+
+import (
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+)
+
+// NewSourceRouter exposes the routing of the backends to the tests, http serves the http backends.
+func NewSourceRouter(backends []Backend, http fetcher.FetchInterface) (fetcher.FetchInterface, error) {
+	return newRouter(backends, backendContext{
+		config:  &Config{},
+		http:    http,
+		content: sniff.New(supportedContentTypes, false),
+	})
+}
@@ -0,0 +1,59 @@
+package imgprev
+
+import (
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/transformer"
+)
+
+// Names of the lru caches, used as the "cache" label.
+const (
+	CacheOriginal = "original"
+	CachePreview  = "preview"
+
+	// CachePreviewMemory the in-memory hot tier of the preview cache.
+	CachePreviewMemory = "preview_memory"
+)
+
+func subscribeCacheMetrics(commandBus bus.CommandBusInterface, registry metrics.RegistryInterface) {
+	hits := registry.Counter("imgproxy_cache_hits_total", "Cache hits.", "cache")
+	misses := registry.Counter("imgproxy_cache_misses_total", "Cache misses.", "cache")
+	evictions := registry.Counter("imgproxy_cache_evictions_total", "Entries removed from the cache.", "cache")
+	size := registry.Gauge("imgproxy_cache_bytes", "Current size of the cache.", "cache")
+
+	on := func(name string, fn func(lru.Event)) {
+		commandBus.Subscribe(name, func(input any) {
+			if event, ok := input.(lru.Event); ok {
+				fn(event)
+			}
+		})
+	}
+
+	on(lru.EventHit, func(e lru.Event) {
+		hits.Inc(e.Cache)
+	})
+
+	on(lru.EventMiss, func(e lru.Event) {
+		misses.Inc(e.Cache)
+	})
+
+	on(lru.EventPut, func(e lru.Event) {
+		size.Set(float64(e.Total), e.Cache)
+	})
+
+	on(lru.EventRemove, func(e lru.Event) {
+		evictions.Inc(e.Cache)
+		size.Set(float64(e.Total), e.Cache)
+	})
+}
+
+func newTransform(registry metrics.RegistryInterface) transformer.TransformInterface {
+	duration := registry.Histogram("imgproxy_transform_duration_seconds",
+		"Duration of the image transformations.", metrics.DefBuckets, "format")
+
+	return transformer.NewStackBy(
+		transformer.NewMetered("jpeg", transformer.NewJpeg(), duration),
+		transformer.NewMetered("png", transformer.NewPng(), duration),
+	)
+}
@@ -0,0 +1,25 @@
+package imgprev
+
+import (
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+type SchemeRule struct {
+	Hosts  []string
+	Scheme string
+}
+
+func newSchemes(fallback string, config []SchemeRule) (fetcher.SchemeInterface, error) {
+	rules := make([]fetcher.SchemeRule, 0, len(config))
+	for _, rule := range config {
+		hosts, err := hostmatch.New(rule.Hosts)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, fetcher.SchemeRule{Hosts: hosts, Scheme: rule.Scheme})
+	}
+
+	return fetcher.NewSchemes(fallback, rules)
+}
@@ -0,0 +1,126 @@
+package imgprev
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/sigv4"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+)
+
+// Backend a named source of the originals. The settings of the other types are ignored.
+type Backend struct {
+	Name string
+	// Type http, local, s3 or data.
+	Type string
+	// Prefixes of the source urls, none means any url.
+	Prefixes []string
+
+	// Roots of the local backend by alias: {prefix}{alias}/path/to/img.jpg, the local backend has one prefix.
+	Roots map[string]string
+
+	S3 struct {
+		Endpoint string
+		Region   string
+		// Buckets the only buckets served, required.
+		Buckets []string
+
+		AccessKey    string `yaml:"accessKey"`
+		SecretKey    string `yaml:"secretKey"`
+		SessionToken string `yaml:"sessionToken"`
+	} `yaml:"s3"`
+}
+
+// backendContext the shared parts of the backends.
+type backendContext struct {
+	config  *Config
+	http    fetcher.FetchInterface
+	content sniff.CheckerInterface
+	maxSize int64
+}
+
+type backendFactory func(backend Backend, ctx backendContext) (fetcher.FetchInterface, error)
+
+// backendFactories a new type of the source is added here.
+var backendFactories = map[string]backendFactory{
+	"http":  newHTTPBackend,
+	"local": newLocalBackend,
+	"s3":    newS3Backend,
+	"data":  newDataBackend,
+}
+
+var defaultBackends = []Backend{
+	{Name: "http", Type: "http", Prefixes: []string{""}},
+	{Name: "data", Type: "data", Prefixes: []string{fetcher.DataScheme}},
+}
+
+func newRouter(backends []Backend, ctx backendContext) (fetcher.FetchInterface, error) {
+	if len(backends) == 0 {
+		backends = defaultBackends
+	}
+
+	var routes []fetcher.Route
+	for _, backend := range backends {
+		factory, ok := backendFactories[backend.Type]
+		if !ok {
+			return nil, fmt.Errorf("backend %s: unknown type %q: %w", backend.Name, backend.Type, fetcher.ErrBadRoute)
+		}
+
+		fetch, err := factory(backend, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backend.Name, err)
+		}
+
+		prefixes := backend.Prefixes
+		if len(prefixes) == 0 {
+			prefixes = []string{""}
+		}
+
+		for _, prefix := range prefixes {
+			routes = append(routes, fetcher.Route{Name: backend.Name, Prefix: prefix, Fetch: fetch})
+		}
+	}
+
+	return fetcher.NewRouter(routes)
+}
+
+// newHTTPBackend every http backend shares the transport and its cache.
+func newHTTPBackend(_ Backend, ctx backendContext) (fetcher.FetchInterface, error) {
+	return ctx.http, nil
+}
+
+// newLocalBackend the prefix is a part of the path of the original, so there is only one.
+func newLocalBackend(backend Backend, ctx backendContext) (fetcher.FetchInterface, error) {
+	if len(backend.Prefixes) > 1 {
+		return nil, fmt.Errorf("local backend with %d prefixes: %w", len(backend.Prefixes), fetcher.ErrBadRoute)
+	}
+
+	prefix := ""
+	if len(backend.Prefixes) > 0 {
+		prefix = backend.Prefixes[0]
+	}
+
+	return fetcher.NewLocalFetcher(prefix, backend.Roots, ctx.content, ctx.maxSize), nil
+}
+
+func newS3Backend(backend Backend, ctx backendContext) (fetcher.FetchInterface, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = ctx.config.Source.Timeouts.Headers
+
+	return fetcher.NewS3Fetcher(fetcher.S3Config{
+		Endpoint: backend.S3.Endpoint,
+		Region:   backend.S3.Region,
+		Buckets:  backend.S3.Buckets,
+		Credentials: sigv4.Credentials{
+			AccessKey:    backend.S3.AccessKey,
+			SecretKey:    backend.S3.SecretKey,
+			SessionToken: backend.S3.SessionToken,
+		},
+	}, transport, totalTimeout(ctx.config.Source.Timeouts.Total), ctx.content, ctx.maxSize)
+}
+
+// newDataBackend the inline images are decoded from the url, the size limit of the source applies too.
+func newDataBackend(_ Backend, ctx backendContext) (fetcher.FetchInterface, error) {
+	return fetcher.NewDataFetcher(ctx.content, ctx.maxSize), nil
+}
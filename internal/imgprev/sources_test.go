@@ -0,0 +1,55 @@
+package imgprev_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/imgprev"
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/stretchr/testify/require"
+)
+
+type web struct{}
+
+func (web) Get(context.Context, string, http.Header) ([]byte, error) {
+	return []byte("web"), nil
+}
+
+func TestNewSourceRouter_Local(t *testing.T) {
+	dir := t.TempDir()
+
+	image, err := os.ReadFile("../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.jpg"), image, 0o600))
+
+	local := imgprev.Backend{Name: "archive", Type: "local", Roots: map[string]string{"img": dir}}
+
+	t.Run("prefix", func(t *testing.T) {
+		local := local
+		local.Prefixes = []string{"local/"}
+
+		router, err := imgprev.NewSourceRouter([]imgprev.Backend{{Name: "http", Type: "http"}, local}, web{})
+		require.NoError(t, err)
+
+		for _, url := range []string{"local/img/a.jpg", "/local/img/a.jpg", "LOCAL/img/a.jpg", "Local/img/a.jpg"} {
+			body, err := router.Get(context.Background(), url, nil)
+			require.NoError(t, err, url)
+			require.Equal(t, image, body, url)
+		}
+
+		body, err := router.Get(context.Background(), "localhost/img/a.jpg", nil)
+		require.NoError(t, err)
+		require.Equal(t, []byte("web"), body)
+	})
+
+	t.Run("several prefixes", func(t *testing.T) {
+		local := local
+		local.Prefixes = []string{"local/", "files/"}
+
+		_, err := imgprev.NewSourceRouter([]imgprev.Backend{local}, web{})
+		require.ErrorIs(t, err, fetcher.ErrBadRoute)
+	})
+}
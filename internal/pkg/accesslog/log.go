@@ -0,0 +1,143 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+)
+
+const (
+	// FormatText key=value fields through the application logger.
+	FormatText = "text"
+	// FormatJSON one json object per request.
+	FormatJSON = "json"
+	// FormatCombined the apache/nginx "combined" line.
+	FormatCombined = "combined"
+)
+
+type Entry struct {
+	Time      time.Time
+	RequestID string
+	ClientIP  string
+	Method    string
+	URI       string
+	Proto     string
+	Status    int
+	Bytes     int64
+	Latency   time.Duration
+	Cache     string
+	Referer   string
+	UserAgent string
+}
+
+type LogInterface interface {
+	Log(Entry)
+}
+
+type impl struct {
+	write func(Entry)
+
+	// sampleRate the share of successful requests to log, failed ones are always logged.
+	sampleRate float64
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// New the text format goes through the application logger, the others are written to the writer as is.
+// The sample rate outside of (0, 1) logs every request.
+func New(format string, sampleRate float64, log logger.LogInterface, writer io.Writer) LogInterface {
+	l := &impl{sampleRate: sampleRate, random: rand.New(rand.NewSource(time.Now().UnixNano()))} //nolint:gosec
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		jsonLog := logger.NewJSON("info", writer)
+		l.write = func(e Entry) {
+			jsonLog.Info("access", fields(e)...)
+		}
+	case FormatCombined:
+		var mu sync.Mutex
+		l.write = func(e Entry) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			_, _ = io.WriteString(writer, combined(e))
+		}
+	default:
+		l.write = func(e Entry) {
+			log.Info("access", fields(e)...)
+		}
+	}
+
+	return l
+}
+
+func (l *impl) Log(e Entry) {
+	if e.Status < 400 && !l.sampled() {
+		return
+	}
+
+	l.write(e)
+}
+
+func (l *impl) sampled() bool {
+	if l.sampleRate <= 0 || l.sampleRate >= 1 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.random.Float64() < l.sampleRate
+}
+
+func fields(e Entry) []any {
+	return []any{
+		"request_id", e.RequestID,
+		"client_ip", e.ClientIP,
+		"method", e.Method,
+		"uri", e.URI,
+		"proto", e.Proto,
+		"status", e.Status,
+		"bytes", e.Bytes,
+		"latency_us", e.Latency.Microseconds(),
+		"cache", e.Cache,
+		"referer", e.Referer,
+		"user_agent", e.UserAgent,
+	}
+}
+
+// combined %h - - [%t] "%r" %>s %b "%{Referer}i" "%{User-agent}i".
+func combined(e Entry) string {
+	size := "-"
+	if e.Bytes > 0 {
+		size = fmt.Sprint(e.Bytes)
+	}
+
+	return fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		dash(e.ClientIP),
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, escape(e.URI), e.Proto,
+		e.Status,
+		size,
+		escape(dash(e.Referer)),
+		escape(dash(e.UserAgent)),
+	)
+}
+
+func dash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`"`, `\"`, "\n", `\n`, `\`, `\\`).Replace(value)
+}
@@ -0,0 +1,81 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/accesslog"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(status int) accesslog.Entry {
+	return accesslog.Entry{
+		Time:      time.Date(2022, time.November, 5, 10, 20, 30, 0, time.UTC),
+		RequestID: "abc",
+		ClientIP:  "10.0.0.1",
+		Method:    "GET",
+		URI:       "/fill/1/1/nginx/a.jpg",
+		Proto:     "HTTP/1.1",
+		Status:    status,
+		Bytes:     637,
+		Latency:   1500 * time.Microsecond,
+		Cache:     "hit",
+		UserAgent: `Go "client"`,
+	}
+}
+
+func TestLog_Formats(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		accesslog.New("", 0, logger.New("info", buffer), nil).Log(entry(200))
+
+		require.Equal(t, "access request_id=abc client_ip=10.0.0.1 method=GET uri=/fill/1/1/nginx/a.jpg "+
+			"proto=HTTP/1.1 status=200 bytes=637 latency_us=1500 cache=hit referer=\"\" "+
+			"user_agent=\"Go \\\"client\\\"\"\n", buffer.String())
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		accesslog.New(accesslog.FormatCombined, 0, logger.Nop(), buffer).Log(entry(200))
+
+		require.Equal(t, "10.0.0.1 - - [05/Nov/2022:10:20:30 +0000] \"GET /fill/1/1/nginx/a.jpg HTTP/1.1\" "+
+			"200 637 \"-\" \"Go \\\"client\\\"\"\n", buffer.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		accesslog.New(accesslog.FormatJSON, 0, logger.Nop(), buffer).Log(entry(502))
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buffer.Bytes(), &record))
+		require.Equal(t, "access", record["msg"])
+		require.Equal(t, "abc", record["request_id"])
+		require.Equal(t, float64(502), record["status"])
+		require.Equal(t, float64(637), record["bytes"])
+		require.Equal(t, "hit", record["cache"])
+	})
+}
+
+func TestLog_Sampling(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log := accesslog.New(accesslog.FormatCombined, 0.5, logger.Nop(), buffer)
+
+	for i := 0; i < 1000; i++ {
+		log.Log(entry(200))
+	}
+
+	successful := strings.Count(buffer.String(), "\n")
+	require.Greater(t, successful, 300)
+	require.Less(t, successful, 700)
+
+	buffer.Reset()
+	for i := 0; i < 100; i++ {
+		log.Log(entry(502))
+	}
+
+	require.Equal(t, 100, strings.Count(buffer.String(), "\n"))
+}
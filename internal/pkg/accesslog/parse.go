@@ -0,0 +1,142 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrFormat = errors.New("not an access log line")
+
+var reCombined = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) ((?:[^"\\]|\\.)*) (\S+)" (\d{3}) (\d+|-)`)
+
+// textMessage the message of the text lines, the fields follow it.
+const textMessage = "access "
+
+// Parse a line of the text, the json or the combined access log, the text one has no time.
+func Parse(line string) (Entry, error) {
+	line = strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(line, "{"):
+		return parseJSON(line)
+	case strings.HasPrefix(line, textMessage):
+		return parseText(line[len(textMessage):])
+	}
+
+	return parseCombined(line)
+}
+
+func parseJSON(line string) (Entry, error) {
+	var record struct {
+		Time      time.Time `json:"time"`
+		Message   string    `json:"msg"`
+		RequestID string    `json:"request_id"`
+		ClientIP  string    `json:"client_ip"`
+		Method    string    `json:"method"`
+		URI       string    `json:"uri"`
+		Proto     string    `json:"proto"`
+		Status    int       `json:"status"`
+		Bytes     int64     `json:"bytes"`
+		LatencyUS int64     `json:"latency_us"`
+		Cache     string    `json:"cache"`
+		Referer   string    `json:"referer"`
+		UserAgent string    `json:"user_agent"`
+	}
+
+	if err := json.Unmarshal([]byte(line), &record); err != nil || record.Message != "access" {
+		return Entry{}, ErrFormat
+	}
+
+	return Entry{
+		Time:      record.Time,
+		RequestID: record.RequestID,
+		ClientIP:  record.ClientIP,
+		Method:    record.Method,
+		URI:       record.URI,
+		Proto:     record.Proto,
+		Status:    record.Status,
+		Bytes:     record.Bytes,
+		Latency:   time.Duration(record.LatencyUS) * time.Microsecond,
+		Cache:     record.Cache,
+		Referer:   record.Referer,
+		UserAgent: record.UserAgent,
+	}, nil
+}
+
+// parseText the key=value fields of the logger, the quoted values are go strings.
+func parseText(line string) (Entry, error) {
+	values := make(map[string]string)
+
+	for line = strings.TrimLeft(line, " "); line != ""; line = strings.TrimLeft(line, " ") {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 || strings.IndexByte(line[:eq], ' ') >= 0 {
+			return Entry{}, ErrFormat
+		}
+
+		key, value := line[:eq], line[eq+1:]
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return Entry{}, ErrFormat
+			}
+
+			line = value[len(quoted):]
+			values[key], _ = strconv.Unquote(quoted)
+
+			continue
+		}
+
+		end := strings.IndexByte(value, ' ')
+		if end < 0 {
+			end = len(value)
+		}
+
+		line = value[end:]
+		values[key] = value[:end]
+	}
+
+	status, err := strconv.Atoi(values["status"])
+	if err != nil || values["method"] == "" || values["uri"] == "" {
+		return Entry{}, ErrFormat
+	}
+
+	entry := Entry{
+		RequestID: values["request_id"],
+		ClientIP:  values["client_ip"],
+		Method:    values["method"],
+		URI:       values["uri"],
+		Proto:     values["proto"],
+		Status:    status,
+		Cache:     values["cache"],
+		Referer:   values["referer"],
+		UserAgent: values["user_agent"],
+	}
+	entry.Bytes, _ = strconv.ParseInt(values["bytes"], 10, 64)
+
+	latency, _ := strconv.ParseInt(values["latency_us"], 10, 64)
+	entry.Latency = time.Duration(latency) * time.Microsecond
+
+	return entry, nil
+}
+
+func parseCombined(line string) (Entry, error) {
+	match := reCombined.FindStringSubmatch(line)
+	if match == nil {
+		return Entry{}, ErrFormat
+	}
+
+	entry := Entry{ClientIP: match[1], Method: match[3], URI: unescape(match[4]), Proto: match[5]}
+	entry.Time, _ = time.Parse("02/Jan/2006:15:04:05 -0700", match[2])
+	entry.Status, _ = strconv.Atoi(match[6])
+	entry.Bytes, _ = strconv.ParseInt(match[7], 10, 64)
+
+	return entry, nil
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\"`, `"`, `\n`, "\n", `\\`, `\`).Replace(value)
+}
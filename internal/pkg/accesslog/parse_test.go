@@ -0,0 +1,70 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/accesslog"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		accesslog.New(accesslog.FormatJSON, 0, logger.Nop(), buffer).Log(entry(200))
+
+		parsed, err := accesslog.Parse(buffer.String())
+		require.NoError(t, err)
+
+		// the json log has the time of the record
+		require.False(t, parsed.Time.IsZero())
+
+		expected := entry(200)
+		parsed.Time = expected.Time
+		require.Equal(t, expected, parsed)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		e := entry(200)
+		e.URI = `/fill/1/1/nginx/a "b" c=d.jpg`
+
+		buffer := &bytes.Buffer{}
+		accesslog.New(accesslog.FormatText, 0, logger.New("info", buffer).With("app", "imgprev"), nil).Log(e)
+
+		// the text log has no time
+		parsed, err := accesslog.Parse(buffer.String())
+		require.NoError(t, err)
+
+		e.Time = time.Time{}
+		require.Equal(t, e, parsed)
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		e := entry(200)
+		e.URI = `/fill/1/1/nginx/a "b".jpg`
+
+		buffer := &bytes.Buffer{}
+		accesslog.New(accesslog.FormatCombined, 0, logger.Nop(), buffer).Log(e)
+
+		parsed, err := accesslog.Parse(buffer.String())
+		require.NoError(t, err)
+		require.Equal(t, "10.0.0.1", parsed.ClientIP)
+		require.Equal(t, "GET", parsed.Method)
+		require.Equal(t, e.URI, parsed.URI)
+		require.Equal(t, 200, parsed.Status)
+		require.Equal(t, int64(637), parsed.Bytes)
+		require.True(t, e.Time.Equal(parsed.Time))
+	})
+
+	t.Run("not an access log", func(t *testing.T) {
+		for _, line := range []string{
+			"", "hello", `{"msg":"started"}`, `{"msg":`,
+			"started addr=:8080", "access status=200", `access method=GET uri="/fill status=200`, "access uri",
+		} {
+			_, err := accesslog.Parse(line)
+			require.ErrorIs(t, err, accesslog.ErrFormat, line)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package accesslog
+
+import (
+	"net/http"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/cachestat"
+)
+
+// ResponseWriter remembers what was sent to the client, so it can be logged.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	cache  *cachestat.Recorder
+}
+
+// NewResponseWriter the recorder may be nil if the cache status is not tracked.
+func NewResponseWriter(w http.ResponseWriter, cache *cachestat.Recorder) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w, cache: cache}
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+func (w *ResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *ResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+func (w *ResponseWriter) Bytes() int64 {
+	return w.bytes
+}
+
+func (w *ResponseWriter) Cache() string {
+	if w.cache == nil {
+		return cachestat.None
+	}
+
+	return w.cache.Status()
+}
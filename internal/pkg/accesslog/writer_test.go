@@ -0,0 +1,62 @@
+package accesslog_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/accesslog"
+	"github.com/rez1dent3/otus-final/internal/pkg/cachestat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseWriter(t *testing.T) {
+	t.Run("implicit status", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := accesslog.NewResponseWriter(recorder, nil)
+
+		require.Equal(t, http.StatusOK, rw.Status())
+
+		_, err := rw.Write([]byte("hello"))
+		require.NoError(t, err)
+		_, err = rw.Write([]byte(" world"))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, rw.Status())
+		require.Equal(t, int64(11), rw.Bytes())
+		require.Equal(t, cachestat.None, rw.Cache())
+		require.Equal(t, "hello world", recorder.Body.String())
+	})
+
+	t.Run("explicit status", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := accesslog.NewResponseWriter(recorder, nil)
+
+		rw.WriteHeader(http.StatusBadGateway)
+		rw.WriteHeader(http.StatusOK)
+
+		require.Equal(t, http.StatusBadGateway, rw.Status())
+		require.Equal(t, int64(0), rw.Bytes())
+		require.Equal(t, http.StatusBadGateway, recorder.Code)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		ctx, cache := cachestat.WithRecorder(context.Background())
+		rw := accesslog.NewResponseWriter(httptest.NewRecorder(), cache)
+
+		require.Equal(t, cachestat.None, rw.Cache())
+
+		cachestat.Mark(ctx, true)
+		require.Equal(t, cachestat.Hit, rw.Cache())
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := accesslog.NewResponseWriter(recorder, nil)
+
+		rw.Flush()
+		require.True(t, recorder.Flushed)
+		require.Equal(t, recorder, rw.Unwrap())
+	})
+}
@@ -0,0 +1,296 @@
+package breaker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+)
+
+const (
+	DefaultThreshold = 5
+	DefaultCoolDown  = 30 * time.Second
+	DefaultProbes    = 1
+
+	DefaultMaxCircuits = 1024
+	DefaultIdleTTL     = 10 * time.Minute
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State uint8
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Options the zero value uses the defaults.
+type Options struct {
+	// Threshold the number of consecutive failures that opens the circuit.
+	Threshold int
+	// CoolDown how long the circuit stays open before a probe request is let through.
+	CoolDown time.Duration
+	// Probes the number of successful probes that closes the circuit again.
+	Probes int
+
+	// MaxCircuits the number of tracked hosts. A new one first removes the circuits idle for IdleTTL,
+	// then the least recently used one, the closed circuits go first.
+	MaxCircuits int
+	IdleTTL     time.Duration
+
+	// IsFailure decides which errors count against the host, nil counts every error.
+	IsFailure func(error) bool
+	// Now the clock, nil means time.Now.
+	Now func() time.Time
+}
+
+type Status struct {
+	Key      string
+	State    State
+	Failures int
+	// Until the end of the cool-down of the open circuit.
+	Until time.Time
+}
+
+// BreakerInterface every allowed request must be finished with Done.
+type BreakerInterface interface {
+	Allow(key string) error
+	Done(key string, err error)
+	Status() []Status
+}
+
+type circuit struct {
+	state     State
+	failures  int
+	successes int
+	probing   bool
+	until     time.Time
+	// seen the last request to the host.
+	seen time.Time
+}
+
+type impl struct {
+	mu       sync.Mutex
+	circuits map[string]*circuit
+
+	options Options
+	log     logger.LogInterface
+}
+
+func New(options Options, log logger.LogInterface) BreakerInterface {
+	if options.Threshold <= 0 {
+		options.Threshold = DefaultThreshold
+	}
+
+	if options.CoolDown <= 0 {
+		options.CoolDown = DefaultCoolDown
+	}
+
+	if options.Probes <= 0 {
+		options.Probes = DefaultProbes
+	}
+
+	if options.MaxCircuits <= 0 {
+		options.MaxCircuits = DefaultMaxCircuits
+	}
+
+	if options.IdleTTL <= 0 {
+		options.IdleTTL = DefaultIdleTTL
+	}
+
+	if options.IsFailure == nil {
+		options.IsFailure = func(err error) bool { return err != nil }
+	}
+
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &impl{circuits: make(map[string]*circuit), options: options, log: log}
+}
+
+// Allow the open circuit fails fast until the cool-down ends, then a single probe at a time is let through.
+func (b *impl) Allow(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return nil
+	}
+
+	c.seen = b.options.Now()
+
+	switch c.state {
+	case StateOpen:
+		if b.options.Now().Before(c.until) {
+			return ErrOpen
+		}
+
+		c.state, c.successes = StateHalfOpen, 0
+		b.log.Info("circuit breaker half-open", "host", key)
+
+		fallthrough
+	case StateHalfOpen:
+		if c.probing {
+			return ErrOpen
+		}
+
+		c.probing = true
+	case StateClosed:
+	}
+
+	return nil
+}
+
+// Done only the failures reported by IsFailure count, other errors are neither a failure nor a success.
+func (b *impl) Done(key string, err error) {
+	failed := err != nil && b.options.IsFailure(err)
+	if err != nil && !failed {
+		b.release(key)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if failed && !ok {
+		if len(b.circuits) >= b.options.MaxCircuits {
+			b.sweep(b.options.Now())
+		}
+
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+
+	if c == nil {
+		return
+	}
+
+	c.seen = b.options.Now()
+
+	if failed {
+		b.failure(key, c)
+	} else {
+		b.success(key, c)
+	}
+}
+
+func (b *impl) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[key]; ok {
+		c.probing = false
+	}
+}
+
+func (b *impl) failure(key string, c *circuit) {
+	c.failures++
+	c.probing = false
+
+	if c.state == StateHalfOpen || (c.state == StateClosed && c.failures >= b.options.Threshold) {
+		c.state = StateOpen
+		c.until = b.options.Now().Add(b.options.CoolDown)
+		b.log.Warning("circuit breaker opened", "host", key, "failures", c.failures, "until", c.until)
+	}
+}
+
+func (b *impl) success(key string, c *circuit) {
+	c.probing = false
+
+	switch c.state {
+	case StateHalfOpen:
+		c.successes++
+		if c.successes < b.options.Probes {
+			return
+		}
+
+		b.log.Info("circuit breaker closed", "host", key)
+		delete(b.circuits, key)
+	case StateClosed:
+		// the failures must be consecutive, the healthy hosts are not tracked
+		delete(b.circuits, key)
+	case StateOpen:
+	}
+}
+
+// sweep removes the idle circuits, then the least recently used ones while the map is full.
+func (b *impl) sweep(now time.Time) {
+	for key, c := range b.circuits {
+		if b.idle(c, now) {
+			delete(b.circuits, key)
+		}
+	}
+
+	for len(b.circuits) >= b.options.MaxCircuits {
+		delete(b.circuits, b.oldest())
+	}
+}
+
+// idle the open circuit is kept until its cool-down ends, the probing one until the probe is done.
+func (b *impl) idle(c *circuit, now time.Time) bool {
+	return !c.probing && !now.Before(c.until) && now.Sub(c.seen) >= b.options.IdleTTL
+}
+
+func (b *impl) oldest() string {
+	var (
+		oldest string
+		seen   *circuit
+	)
+
+	for key, c := range b.circuits {
+		if seen == nil || older(c, seen) {
+			oldest, seen = key, c
+		}
+	}
+
+	return oldest
+}
+
+// older the closed circuits are evicted before the open ones.
+func older(c, than *circuit) bool {
+	if (c.state == StateClosed) != (than.state == StateClosed) {
+		return c.state == StateClosed
+	}
+
+	return c.seen.Before(than.seen)
+}
+
+// Status the hosts with failures, sorted by key.
+func (b *impl) Status() []Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]Status, 0, len(b.circuits))
+	for key, c := range b.circuits {
+		status := Status{Key: key, State: c.state, Failures: c.failures}
+		if c.state == StateOpen {
+			status.Until = c.until
+		}
+
+		result = append(result, status)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
@@ -0,0 +1,185 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+var errDown = errors.New("connection refused")
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newBreaker(options breaker.Options) (breaker.BreakerInterface, *clock) {
+	fake := &clock{now: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)}
+	options.Now = fake.Now
+
+	return breaker.New(options, logger.Nop()), fake
+}
+
+func fail(t *testing.T, b breaker.BreakerInterface, key string, times int) {
+	t.Helper()
+
+	for i := 0; i < times; i++ {
+		require.NoError(t, b.Allow(key))
+		b.Done(key, errDown)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		b, _ := newBreaker(breaker.Options{Threshold: 3, CoolDown: time.Minute})
+
+		fail(t, b, "a.com", 2)
+		require.NoError(t, b.Allow("a.com"))
+		b.Done("a.com", nil)
+
+		// the success resets the counter
+		require.Empty(t, b.Status())
+
+		fail(t, b, "a.com", 3)
+		require.ErrorIs(t, b.Allow("a.com"), breaker.ErrOpen)
+		require.NoError(t, b.Allow("b.com"))
+	})
+
+	t.Run("half-open probe closes", func(t *testing.T) {
+		b, fake := newBreaker(breaker.Options{Threshold: 1, CoolDown: time.Minute})
+
+		fail(t, b, "a.com", 1)
+		fake.Advance(59 * time.Second)
+		require.ErrorIs(t, b.Allow("a.com"), breaker.ErrOpen)
+
+		fake.Advance(time.Second)
+		require.NoError(t, b.Allow("a.com"))
+
+		// a single probe at a time
+		require.ErrorIs(t, b.Allow("a.com"), breaker.ErrOpen)
+		require.Equal(t, breaker.StateHalfOpen, b.Status()[0].State)
+
+		b.Done("a.com", nil)
+		require.Empty(t, b.Status())
+		require.NoError(t, b.Allow("a.com"))
+	})
+
+	t.Run("half-open probe reopens", func(t *testing.T) {
+		b, fake := newBreaker(breaker.Options{Threshold: 1, CoolDown: time.Minute})
+
+		fail(t, b, "a.com", 1)
+		fake.Advance(time.Minute)
+		fail(t, b, "a.com", 1)
+
+		status := b.Status()
+		require.Len(t, status, 1)
+		require.Equal(t, breaker.StateOpen, status[0].State)
+		require.Equal(t, 2, status[0].Failures)
+		require.Equal(t, fake.Now().Add(time.Minute), status[0].Until)
+		require.ErrorIs(t, b.Allow("a.com"), breaker.ErrOpen)
+	})
+
+	t.Run("probes", func(t *testing.T) {
+		b, fake := newBreaker(breaker.Options{Threshold: 1, CoolDown: time.Minute, Probes: 2})
+
+		fail(t, b, "a.com", 1)
+		fake.Advance(time.Minute)
+
+		require.NoError(t, b.Allow("a.com"))
+		b.Done("a.com", nil)
+		require.Equal(t, breaker.StateHalfOpen, b.Status()[0].State)
+
+		require.NoError(t, b.Allow("a.com"))
+		b.Done("a.com", nil)
+		require.Empty(t, b.Status())
+	})
+
+	t.Run("ignored errors", func(t *testing.T) {
+		b, fake := newBreaker(breaker.Options{
+			Threshold: 1,
+			CoolDown:  time.Minute,
+			IsFailure: func(err error) bool { return !errors.Is(err, context.Canceled) },
+		})
+
+		require.NoError(t, b.Allow("a.com"))
+		b.Done("a.com", context.Canceled)
+		require.Empty(t, b.Status())
+
+		fail(t, b, "a.com", 1)
+		fake.Advance(time.Minute)
+
+		// the probe is released, but the circuit stays half-open
+		require.NoError(t, b.Allow("a.com"))
+		b.Done("a.com", context.Canceled)
+		require.NoError(t, b.Allow("a.com"))
+		require.Equal(t, breaker.StateHalfOpen, b.Status()[0].State)
+	})
+
+	t.Run("status", func(t *testing.T) {
+		b, _ := newBreaker(breaker.Options{Threshold: 2})
+
+		fail(t, b, "b.com", 2)
+		fail(t, b, "a.com", 1)
+
+		status := b.Status()
+		require.Len(t, status, 2)
+		require.Equal(t, "a.com", status[0].Key)
+		require.Equal(t, breaker.StateClosed, status[0].State)
+		require.True(t, status[0].Until.IsZero())
+		require.Equal(t, "b.com", status[1].Key)
+		require.Equal(t, "open", status[1].State.String())
+	})
+
+	t.Run("idle circuits", func(t *testing.T) {
+		b, fake := newBreaker(breaker.Options{Threshold: 2, CoolDown: time.Hour, MaxCircuits: 3, IdleTTL: time.Minute})
+
+		fail(t, b, "open.com", 2)
+		fail(t, b, "a.com", 1)
+		fake.Advance(time.Minute)
+
+		// the idle circuits are removed once the map is full, the open one stays until its cool-down ends
+		fail(t, b, "b.com", 1)
+		fail(t, b, "c.com", 1)
+
+		status := b.Status()
+		require.Len(t, status, 3)
+		require.Equal(t, []string{"b.com", "c.com", "open.com"}, keys(status))
+	})
+
+	t.Run("max circuits", func(t *testing.T) {
+		b, _ := newBreaker(breaker.Options{Threshold: 2, MaxCircuits: 10})
+
+		fail(t, b, "open.com", 2)
+
+		for i := 0; i < 1000; i++ {
+			fail(t, b, strconv.Itoa(i)+".invalid", 1)
+			require.LessOrEqual(t, len(b.Status()), 10)
+		}
+
+		// the closed circuits are evicted first
+		require.ErrorIs(t, b.Allow("open.com"), breaker.ErrOpen)
+		require.Contains(t, keys(b.Status()), "999.invalid")
+	})
+}
+
+func keys(status []breaker.Status) []string {
+	result := make([]string, 0, len(status))
+	for _, s := range status {
+		result = append(result, s.Key)
+	}
+
+	return result
+}
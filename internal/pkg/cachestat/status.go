@@ -0,0 +1,48 @@
+package cachestat
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+const (
+	None = ""
+	Hit  = "hit"
+	Miss = "miss"
+)
+
+type ctxKey struct{}
+
+// Recorder keeps whether the response was served from the cache, it is set deep in the use case
+// and read by the server once the request is handled.
+type Recorder struct {
+	status atomic.Value
+}
+
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{}
+
+	return context.WithValue(ctx, ctxKey{}, recorder), recorder
+}
+
+// Mark does nothing if the context has no recorder.
+func Mark(ctx context.Context, hit bool) {
+	recorder, ok := ctx.Value(ctxKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+
+	if hit {
+		recorder.status.Store(Hit)
+	} else {
+		recorder.status.Store(Miss)
+	}
+}
+
+func (r *Recorder) Status() string {
+	if status, ok := r.status.Load().(string); ok {
+		return status
+	}
+
+	return None
+}
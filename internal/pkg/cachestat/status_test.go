@@ -0,0 +1,28 @@
+package cachestat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/cachestat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("without recorder", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			cachestat.Mark(context.Background(), true)
+		})
+	})
+
+	t.Run("marks", func(t *testing.T) {
+		ctx, recorder := cachestat.WithRecorder(context.Background())
+		require.Equal(t, cachestat.None, recorder.Status())
+
+		cachestat.Mark(ctx, false)
+		require.Equal(t, cachestat.Miss, recorder.Status())
+
+		cachestat.Mark(ctx, true)
+		require.Equal(t, cachestat.Hit, recorder.Status())
+	})
+}
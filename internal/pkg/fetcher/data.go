@@ -0,0 +1,77 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+)
+
+const DataScheme = "data:"
+
+// NewDataFetcher decodes the inline images: data:image/png;base64,iVBORw0KGgo...
+func NewDataFetcher(content sniff.CheckerInterface, maxSize int64) FetchInterface {
+	return &dataImpl{content: content, maxSize: maxSize}
+}
+
+type dataImpl struct {
+	content sniff.CheckerInterface
+	maxSize int64
+}
+
+func (f *dataImpl) Get(_ context.Context, rawURL string, _ http.Header) ([]byte, error) {
+	rawURL = strings.TrimPrefix(rawURL, "/")
+	if len(rawURL) < len(DataScheme) || !strings.EqualFold(rawURL[:len(DataScheme)], DataScheme) {
+		return nil, fmt.Errorf("not a data uri: %w", ErrBadPath)
+	}
+
+	meta, data, ok := strings.Cut(rawURL[len(DataScheme):], ",")
+	if !ok {
+		return nil, fmt.Errorf("data uri without data: %w", ErrBadPath)
+	}
+
+	mediaType, encoded := meta, false
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		mediaType, encoded = meta[:len(meta)-len(";base64")], true
+	}
+
+	// the encoded size is known before decoding, base64 takes 4 bytes for 3
+	if f.maxSize > 0 && int64(len(data)) > f.maxSize*4/3+4 {
+		return nil, fmt.Errorf("data uri: %w", sizelimit.ErrTooLarge)
+	}
+
+	body, err := decodeData(data, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data uri: %w", ErrBadPath)
+	}
+
+	if f.maxSize > 0 && int64(len(body)) > f.maxSize {
+		return nil, fmt.Errorf("data uri: %w", sizelimit.ErrTooLarge)
+	}
+
+	if _, err := f.content.Check(mediaType, body); err != nil {
+		return nil, fmt.Errorf("unexpected content: %w", err)
+	}
+
+	return body, nil
+}
+
+// decodeData accepts both base64 alphabets, with or without the padding.
+func decodeData(data string, encoded bool) ([]byte, error) {
+	data, err := url.PathUnescape(data)
+	if err != nil || !encoded {
+		return []byte(data), err
+	}
+
+	data = strings.TrimRight(data, "=")
+	if strings.ContainsAny(data, "-_") {
+		return base64.RawURLEncoding.DecodeString(data)
+	}
+
+	return base64.RawStdEncoding.DecodeString(data)
+}
@@ -0,0 +1,70 @@
+package fetcher_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataImpl_Get(t *testing.T) {
+	jpeg, err := os.ReadFile("../../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+
+	std := base64.StdEncoding.EncodeToString(jpeg)
+	urlSafe := base64.RawURLEncoding.EncodeToString(jpeg)
+
+	fetch := fetcher.NewDataFetcher(sniff.New([]string{"image/jpeg"}, false), 0)
+
+	testCases := []struct {
+		url string
+		err error
+	}{
+		{"data:image/jpeg;base64," + std, nil},
+		{"/data:image/jpeg;base64," + std, nil},
+		{"DATA:image/jpeg;BASE64," + urlSafe, nil},
+		{"data:;base64," + std, nil},
+		{"data:image/png;base64," + std, nil},
+		{"data:image/jpeg;base64,%%%", fetcher.ErrBadPath},
+		{"data:image/jpeg;base64,!!!!", fetcher.ErrBadPath},
+		{"data:image/jpeg;base64", fetcher.ErrBadPath},
+		{"data:text/plain,hello", fetcher.ErrNotSupportedContentType},
+		{"http://example.com", fetcher.ErrBadPath},
+	}
+
+	for _, testCase := range testCases {
+		body, err := fetch.Get(context.Background(), testCase.url, nil)
+		if testCase.err != nil {
+			require.ErrorIs(t, err, testCase.err, testCase.url)
+			continue
+		}
+
+		require.NoError(t, err, testCase.url)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+	}
+
+	t.Run("query", func(t *testing.T) {
+		body, err := fetch.Get(fetcher.WithQuery(context.Background(), "v=3"), "data:image/jpeg;base64,"+std, nil)
+		require.NoError(t, err)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		fetch := fetcher.NewDataFetcher(sniff.New([]string{"image/jpeg"}, false), 1024)
+
+		_, err := fetch.Get(context.Background(), "data:image/jpeg;base64,"+std, nil)
+		require.ErrorIs(t, err, fetcher.ErrTooLarge)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		fetch := fetcher.NewDataFetcher(sniff.New([]string{"image/jpeg"}, true), 0)
+
+		_, err := fetch.Get(context.Background(), "data:image/png;base64,"+std, nil)
+		require.ErrorIs(t, err, fetcher.ErrContentTypeMismatch)
+	})
+}
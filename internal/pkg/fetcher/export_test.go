@@ -0,0 +1,11 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+)
+
+// Prepare exposes the request preparation of the http fetcher to the tests.
+func Prepare(ctx context.Context, fetch FetchInterface, rawURL string, header http.Header) (*http.Request, error) {
+	return fetch.(*httpImpl).prepare(ctx, rawURL, header)
+}
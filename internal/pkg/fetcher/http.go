@@ -2,16 +2,26 @@ package fetcher
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/headerpolicy"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/requestid"
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
 )
 
-var ErrNotSupportedContentType = errors.New("fetcher does not support content-type")
+var (
+	ErrNotSupportedContentType = sniff.ErrNotSupported
+	ErrContentTypeMismatch     = sniff.ErrMismatch
+	ErrHostNotAllowed          = hostmatch.ErrNotAllowed
+	ErrTooLarge                = sizelimit.ErrTooLarge
+)
 
 type FetchInterface interface {
 	Get(context.Context, string, http.Header) ([]byte, error)
@@ -20,20 +30,31 @@ type FetchInterface interface {
 func NewHTTPFetcher(
 	transport http.RoundTripper,
 	timeout time.Duration,
-	supportedContentTypes []string,
+	content sniff.CheckerInterface,
+	hosts hostmatch.PolicyInterface,
+	maxSize int64,
+	headers headerpolicy.PolicyInterface,
+	schemes SchemeInterface,
 ) FetchInterface {
 	return &httpImpl{
-		transport:             transport,
-		Timeout:               timeout,
-		SupportedContentTypes: supportedContentTypes,
+		transport: transport,
+		hosts:     hosts,
+		headers:   headers,
+		schemes:   schemes,
+		content:   content,
+		maxSize:   maxSize,
+		Timeout:   timeout,
 	}
 }
 
 type httpImpl struct {
 	transport http.RoundTripper
+	hosts     hostmatch.PolicyInterface
+	headers   headerpolicy.PolicyInterface
+	schemes   SchemeInterface
+	content   sniff.CheckerInterface
+	maxSize   int64
 	Timeout   time.Duration
-
-	SupportedContentTypes []string
 }
 
 func (f *httpImpl) Get(ctx context.Context, url string, header http.Header) ([]byte, error) {
@@ -50,27 +71,66 @@ func (f *httpImpl) Get(ctx context.Context, url string, header http.Header) ([]b
 	return responseBody, nil
 }
 
+// Validator the query passed to the origin, the same url with another query is another original.
+func (f *httpImpl) Validator(ctx context.Context, _ string) (string, error) {
+	return QueryFromContext(ctx), nil
+}
+
 func (f *httpImpl) prepare(ctx context.Context, rawURL string, header http.Header) (*http.Request, error) {
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	scheme, rest := splitScheme(strings.TrimPrefix(rawURL, "/"))
+
+	parsedURL, err := url.Parse("//" + rest)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create proxy request: %w", err)
+		return nil, fmt.Errorf("failed to parse url: %w", err)
 	}
 
-	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
-		rawURL = "http://" + rawURL
+	parsedURL.Scheme = scheme
+	if parsedURL.Scheme == "" {
+		parsedURL.Scheme = f.scheme(parsedURL.Host)
 	}
 
-	parsedURL, err := url.Parse(rawURL)
+	if query := QueryFromContext(ctx); query != "" {
+		if parsedURL.RawQuery != "" {
+			query = parsedURL.RawQuery + "&" + query
+		}
+
+		parsedURL.RawQuery = query
+	}
+
+	if !f.hosts.Allowed(parsedURL.Host) {
+		return nil, fmt.Errorf("%s: %w", parsedURL.Hostname(), ErrHostNotAllowed)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, parsedURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse url: %w", err)
+		return nil, fmt.Errorf("failed to create proxy request: %w", err)
 	}
 
-	request.URL = parsedURL
-	request.Header = header
+	// nil policy forwards the client headers as is
+	if f.headers != nil {
+		request.Header = f.headers.Apply(parsedURL.Host, header)
+	} else if header != nil {
+		request.Header = header.Clone()
+	}
+
+	if id := requestid.FromContext(ctx); id != "" {
+		request.Header.Set(requestid.Header, id)
+	}
+
+	logger.FromContext(ctx, logger.Nop()).Debug("fetching original", "url", parsedURL.String())
 
 	return request, nil
 }
 
+// scheme nil policy means DefaultScheme for every host.
+func (f *httpImpl) scheme(host string) string {
+	if f.schemes == nil {
+		return DefaultScheme
+	}
+
+	return f.schemes.Scheme(host)
+}
+
 func (f *httpImpl) do(request *http.Request) ([]byte, error) {
 	client := http.Client{
 		Timeout:   f.Timeout,
@@ -86,25 +146,14 @@ func (f *httpImpl) do(request *http.Request) ([]byte, error) {
 		_ = resp.Body.Close()
 	}()
 
-	supported := false
-	responseContentType := resp.Header.Get("Content-Type")
-	for _, contentType := range f.SupportedContentTypes {
-		if strings.Contains(responseContentType, contentType) {
-			supported = true
-			break
-		}
-	}
-
-	if !supported {
-		return nil, fmt.Errorf(
-			"not supported content-type %s: %w",
-			responseContentType, ErrNotSupportedContentType)
-	}
-
-	buff, err := io.ReadAll(resp.Body)
+	buff, err := sizelimit.ReadAll(resp.Body, resp.ContentLength, f.maxSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read request body: %w", err)
 	}
 
+	if _, err := f.content.Check(resp.Header.Get("Content-Type"), buff); err != nil {
+		return nil, fmt.Errorf("unexpected content: %w", err)
+	}
+
 	return buff, nil
 }
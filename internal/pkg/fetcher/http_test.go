@@ -1,15 +1,25 @@
 package fetcher_test
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
 	"net/url"
+	"os"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/headerpolicy"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/requestid"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,6 +38,15 @@ func fileServer() *httptest.Server {
 	return httptest.NewServer(checkAuth(http.FileServer(http.Dir("../../../resources/images"))))
 }
 
+func anyHost(t *testing.T) hostmatch.PolicyInterface {
+	t.Helper()
+
+	policy, err := hostmatch.NewPolicy(nil, nil)
+	require.NoError(t, err)
+
+	return policy
+}
+
 func TestHttpImpl_Get(t *testing.T) {
 	t.Run("httpauth", func(t *testing.T) {
 		testCases := []struct {
@@ -66,7 +85,11 @@ func TestHttpImpl_Get(t *testing.T) {
 		fetch := fetcher.NewHTTPFetcher(
 			&http.Transport{},
 			50*time.Millisecond,
-			[]string{"image/jpeg", "image/png"},
+			sniff.New([]string{"image/jpeg", "image/png"}, false),
+			anyHost(t),
+			0,
+			nil,
+			nil,
 		)
 
 		server := fileServer()
@@ -97,7 +120,11 @@ func TestHttpImpl_Get(t *testing.T) {
 		fetch := fetcher.NewHTTPFetcher(
 			&http.Transport{},
 			50*time.Millisecond,
-			[]string{"image/jpeg", "image/png"},
+			sniff.New([]string{"image/jpeg", "image/png"}, false),
+			anyHost(t),
+			0,
+			nil,
+			nil,
 		)
 
 		server := fileServer()
@@ -116,4 +143,360 @@ func TestHttpImpl_Get(t *testing.T) {
 			require.Equal(t, testCase.expected, hsum.New().Hash(bytes))
 		}
 	})
+	t.Run("request id", func(t *testing.T) {
+		var received string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Get(requestid.Header)
+			http.ServeFile(w, r, "../../../resources/images/_gopher_original_1024x504.jpg")
+		}))
+		defer server.Close()
+
+		fetch := fetcher.NewHTTPFetcher(
+			&http.Transport{},
+			time.Second,
+			sniff.New([]string{"image/jpeg"}, false),
+			anyHost(t),
+			0,
+			nil,
+			nil,
+		)
+
+		header := http.Header{}
+		header.Set(requestid.Header, "from-client")
+		ctx := requestid.WithContext(context.Background(), "abc")
+
+		_, err := fetch.Get(ctx, server.URL, header)
+		require.NoError(t, err)
+		require.Equal(t, "abc", received)
+		require.Equal(t, "from-client", header.Get(requestid.Header))
+	})
+}
+
+func TestHttpImpl_Prepare(t *testing.T) {
+	policy, err := hostmatch.NewPolicy(
+		[]string{"cdn.example.com", "*.media.example.com", "10.0.0.0/8"},
+		[]string{"private.media.example.com", "10.0.0.1"},
+	)
+	require.NoError(t, err)
+
+	fetch := fetcher.NewHTTPFetcher(
+		&http.Transport{},
+		time.Second,
+		sniff.New([]string{"image/jpeg"}, false),
+		policy,
+		0,
+		nil,
+		nil,
+	)
+
+	testCases := []struct {
+		rawURL   string
+		allowed  bool
+		expected string
+	}{
+		{"cdn.example.com/a.jpg", true, "https://cdn.example.com/a.jpg"},
+		{"https://cdn.example.com/a.jpg", true, "https://cdn.example.com/a.jpg"},
+		{"http://cdn.example.com/a.jpg", true, "http://cdn.example.com/a.jpg"},
+		{"img.media.example.com/a.jpg", true, "https://img.media.example.com/a.jpg"},
+		{"10.1.2.3:8080/a.jpg", true, "https://10.1.2.3:8080/a.jpg"},
+		{"CDN.EXAMPLE.COM/a.jpg", true, "https://CDN.EXAMPLE.COM/a.jpg"},
+		{"example.com/a.jpg", false, ""},
+		{"media.example.com/a.jpg", false, ""},
+		{"private.media.example.com/a.jpg", false, ""},
+		{"10.0.0.1/a.jpg", false, ""},
+		{"192.168.0.1/a.jpg", false, ""},
+		{"user@cdn.example.com.evil.com/a.jpg", false, ""},
+	}
+
+	for _, testCase := range testCases {
+		req, err := fetcher.Prepare(context.Background(), fetch, testCase.rawURL, nil)
+		if !testCase.allowed {
+			require.ErrorIs(t, err, fetcher.ErrHostNotAllowed, testCase.rawURL)
+			require.Nil(t, req)
+			continue
+		}
+
+		require.NoError(t, err, testCase.rawURL)
+		require.Equal(t, testCase.expected, req.URL.String())
+	}
+}
+
+// rawServer answers every connection with the raw response, so it can lie about the length.
+func rawServer(t *testing.T, head string, body []byte) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+
+				_, _ = bufio.NewReader(conn).ReadString('\n')
+				_, _ = conn.Write([]byte(head + "\r\n"))
+				_, _ = conn.Write(body)
+			}()
+		}
+	}()
+
+	return "http://" + listener.Addr().String() + "/image.jpg"
+}
+
+func TestHttpImpl_Scheme(t *testing.T) {
+	internal, err := hostmatch.New([]string{"*.internal", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	schemes, err := fetcher.NewSchemes("https", []fetcher.SchemeRule{{Hosts: internal, Scheme: "http"}})
+	require.NoError(t, err)
+
+	fetch := fetcher.NewHTTPFetcher(
+		&http.Transport{},
+		time.Second,
+		sniff.New([]string{"image/jpeg"}, false),
+		anyHost(t),
+		0,
+		nil,
+		schemes,
+	)
+
+	testCases := []struct {
+		rawURL   string
+		expected string
+	}{
+		// the default scheme of the host
+		{"cdn.example.com/a.jpg", "https://cdn.example.com/a.jpg"},
+		{"img.internal/a.jpg", "http://img.internal/a.jpg"},
+		{"10.1.2.3:8080/a.jpg", "http://10.1.2.3:8080/a.jpg"},
+
+		// the explicit scheme wins
+		{"http://cdn.example.com/a.jpg", "http://cdn.example.com/a.jpg"},
+		{"https://img.internal/a.jpg", "https://img.internal/a.jpg"},
+		{"http:/cdn.example.com/a.jpg", "http://cdn.example.com/a.jpg"},
+		{"HTTPS:/img.internal/a.jpg", "https://img.internal/a.jpg"},
+
+		// the scheme segment of the route
+		{"http/cdn.example.com/a.jpg", "http://cdn.example.com/a.jpg"},
+		{"https/img.internal/a.jpg?v=1", "https://img.internal/a.jpg?v=1"},
+		{"/https/img.internal/a.jpg", "https://img.internal/a.jpg"},
+
+		// a host that only looks like a scheme
+		{"https.example.com/a.jpg", "https://https.example.com/a.jpg"},
+		{"httpbin.internal/a.jpg", "http://httpbin.internal/a.jpg"},
+	}
+
+	for _, testCase := range testCases {
+		req, err := fetcher.Prepare(context.Background(), fetch, testCase.rawURL, nil)
+		require.NoError(t, err, testCase.rawURL)
+		require.Equal(t, testCase.expected, req.URL.String(), testCase.rawURL)
+	}
+
+	t.Run("not supported", func(t *testing.T) {
+		_, err := fetcher.NewSchemes("ftp", nil)
+		require.ErrorIs(t, err, fetcher.ErrNotSupportedScheme)
+
+		_, err = fetcher.NewSchemes("", []fetcher.SchemeRule{{Hosts: internal, Scheme: "gopher"}})
+		require.ErrorIs(t, err, fetcher.ErrNotSupportedScheme)
+	})
+}
+
+func TestHttpImpl_Query(t *testing.T) {
+	fetch := fetcher.NewHTTPFetcher(
+		&http.Transport{},
+		time.Second,
+		sniff.New([]string{"image/jpeg"}, false),
+		anyHost(t),
+		0,
+		nil,
+		nil,
+	)
+	ctx := fetcher.WithQuery(context.Background(), "v=3&token=a%2Bb")
+
+	req, err := fetcher.Prepare(ctx, fetch, "cdn.example.com/a.jpg", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/a.jpg?v=3&token=a%2Bb", req.URL.String())
+
+	// the query of the encoded url comes first
+	req, err = fetcher.Prepare(ctx, fetch, "https://cdn.example.com/a.jpg?w=1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/a.jpg?w=1&v=3&token=a%2Bb", req.URL.String())
+
+	// the query is a part of the original
+	validator, ok := fetch.(fetcher.ValidatorInterface)
+	require.True(t, ok)
+
+	value, err := validator.Validator(ctx, "cdn.example.com/a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "v=3&token=a%2Bb", value)
+}
+
+func TestHttpImpl_MaxSize(t *testing.T) {
+	image, err := os.ReadFile("../../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+
+	chunked := &bytes.Buffer{}
+	writer := httputil.NewChunkedWriter(chunked)
+	_, _ = writer.Write(image)
+	_ = writer.Close()
+	chunked.WriteString("\r\n")
+
+	testCases := []struct {
+		name     string
+		head     string
+		body     []byte
+		limit    int64
+		tooLarge bool
+	}{
+		{
+			"honest",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/jpeg\r\nContent-Length: " + strconv.Itoa(len(image)) + "\r\n",
+			image, int64(len(image)), false,
+		},
+		{
+			"declared too large",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/jpeg\r\nContent-Length: " + strconv.Itoa(len(image)) + "\r\n",
+			image, 1024, true,
+		},
+		{
+			"no length",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/jpeg\r\nConnection: close\r\n",
+			image, 1024, true,
+		},
+		{
+			"chunked with a false length",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/jpeg\r\nContent-Length: 10\r\nTransfer-Encoding: chunked\r\n",
+			chunked.Bytes(), 1024, true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			rawURL := rawServer(t, testCase.head, testCase.body)
+			fetch := fetcher.NewHTTPFetcher(&http.Transport{}, time.Second, sniff.New([]string{"image/jpeg"}, false), anyHost(t),
+				testCase.limit, nil, nil)
+
+			body, err := fetch.Get(context.Background(), rawURL, nil)
+			if testCase.tooLarge {
+				require.ErrorIs(t, err, fetcher.ErrTooLarge)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, image, body)
+		})
+	}
+}
+
+func TestHttpImpl_PrepareHeaders(t *testing.T) {
+	headers, err := headerpolicy.New(headerpolicy.Config{
+		Denied: []string{"Cookie"},
+		Rename: map[string]string{"X-Client": "X-Device"},
+		Hosts: []headerpolicy.HostRule{
+			{
+				Hosts:   []string{"cdn.example.com"},
+				Forward: []string{"Authorization"},
+				Set:     map[string]string{"User-Agent": "imgproxy"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	fetch := fetcher.NewHTTPFetcher(
+		&http.Transport{},
+		time.Second,
+		sniff.New([]string{"image/jpeg"}, false),
+		anyHost(t),
+		0,
+		headers,
+		nil,
+	)
+	ctx := requestid.WithContext(context.Background(), "abc")
+
+	header := http.Header{}
+	header.Set("Authorization", "Basic dXNlcjp1c2Vy")
+	header.Set("Cookie", "session=1")
+	header.Set("Connection", "close")
+	header.Set("Accept-Encoding", "gzip")
+	header.Set("If-Modified-Since", "Mon, 02 Jan 2006 15:04:05 GMT")
+	header.Set("User-Agent", "curl")
+	header.Set("X-Client", "mobile")
+	header.Set(requestid.Header, "from-client")
+
+	req, err := fetcher.Prepare(ctx, fetch, "cdn.example.com/a.jpg", header)
+	require.NoError(t, err)
+
+	expected := http.Header{}
+	expected.Set("Authorization", "Basic dXNlcjp1c2Vy")
+	expected.Set("User-Agent", "imgproxy")
+	expected.Set("X-Device", "mobile")
+	expected.Set(requestid.Header, "abc")
+	require.Equal(t, expected, req.Header)
+
+	req, err = fetcher.Prepare(ctx, fetch, "other.example.com/a.jpg", header)
+	require.NoError(t, err)
+	require.Equal(t, "curl", req.Header.Get("User-Agent"))
+	require.Empty(t, req.Header.Get("Authorization"))
+
+	// without the policy the headers are forwarded as is
+	fetch = fetcher.NewHTTPFetcher(
+		&http.Transport{},
+		time.Second,
+		sniff.New([]string{"image/jpeg"}, false),
+		anyHost(t),
+		0,
+		nil,
+		nil,
+	)
+
+	req, err = fetcher.Prepare(context.Background(), fetch, "cdn.example.com/a.jpg", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{}, req.Header)
+}
+
+func TestHttpImpl_ContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		http.ServeFile(w, r, "../../../resources/images/"+r.URL.Query().Get("name"))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		declared string
+		strict   bool
+		err      error
+	}{
+		{"_gopher_original_1024x504.jpg", "application/octet-stream", false, nil},
+		{"_gopher_original_1024x504.jpg", "image/png", false, nil},
+		{"_gopher_original_1024x504.jpg", "image/jpeg", true, nil},
+		{"_gopher_original_1024x504.jpg", "application/octet-stream", true, fetcher.ErrContentTypeMismatch},
+		{"_gopher_original_1024x504.webp", "image/jpeg", false, fetcher.ErrNotSupportedContentType},
+		{"_gopher_original_1024x504.webp", "image/webp", true, fetcher.ErrNotSupportedContentType},
+	}
+
+	for _, testCase := range testCases {
+		content := sniff.New([]string{"image/jpeg", "image/png"}, testCase.strict)
+		fetch := fetcher.NewHTTPFetcher(&http.Transport{}, time.Second, content, anyHost(t), 0, nil, nil)
+
+		query := url.Values{"name": {testCase.name}, "type": {testCase.declared}}
+		_, err := fetch.Get(context.Background(), server.URL+"/?"+query.Encode(), nil)
+		if testCase.err == nil {
+			require.NoError(t, err, testCase.declared)
+			continue
+		}
+
+		require.ErrorIs(t, err, testCase.err, testCase.declared)
+	}
 }
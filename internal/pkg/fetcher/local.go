@@ -0,0 +1,156 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+)
+
+const LocalPrefix = "local/"
+
+var (
+	ErrNotFound = errors.New("source not found")
+	ErrBadPath  = errors.New("source path is not allowed")
+)
+
+// ValidatorInterface is implemented by the sources that can tell cheaply whether the original has changed.
+type ValidatorInterface interface {
+	// Validator changes whenever the original does.
+	Validator(ctx context.Context, url string) (string, error)
+}
+
+// NewLocalFetcher serves the originals from the root directories by alias: {prefix}{alias}/path/to/img.jpg,
+// the empty prefix means LocalPrefix.
+func NewLocalFetcher(
+	prefix string,
+	roots map[string]string,
+	content sniff.CheckerInterface,
+	maxSize int64,
+) FetchInterface {
+	if prefix == "" {
+		prefix = LocalPrefix
+	}
+
+	return &localImpl{prefix: prefix, roots: roots, content: content, maxSize: maxSize}
+}
+
+type localImpl struct {
+	prefix  string
+	roots   map[string]string
+	content sniff.CheckerInterface
+	maxSize int64
+}
+
+func (f *localImpl) Get(ctx context.Context, url string, _ http.Header) ([]byte, error) {
+	name, err := f.resolve(url)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, logger.Nop()).Debug("reading original", "url", url, "path", name)
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open original: %w", notFound(err))
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat original: %w", notFound(err))
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s: %w", url, ErrNotFound)
+	}
+
+	body, err := sizelimit.ReadAll(file, info.Size(), f.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original: %w", err)
+	}
+
+	if _, err := f.content.Check(mime.TypeByExtension(filepath.Ext(name)), body); err != nil {
+		return nil, fmt.Errorf("unexpected content: %w", err)
+	}
+
+	return body, nil
+}
+
+// Validator the modification time and the size of the file.
+func (f *localImpl) Validator(_ context.Context, url string) (string, error) {
+	name, err := f.resolve(url)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", notFound(err)
+	}
+
+	return strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36), nil
+}
+
+// resolve the path inside the root of the alias, neither ".." nor a symlink can leave the root.
+func (f *localImpl) resolve(url string) (string, error) {
+	trimmed, ok := trimPrefix(url, f.prefix)
+	if !ok {
+		return "", fmt.Errorf("%s: %w", url, ErrBadPath)
+	}
+
+	alias, rest, ok := strings.Cut(trimmed, "/")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("%s: %w", url, ErrBadPath)
+	}
+
+	root, ok := f.roots[alias]
+	if !ok {
+		return "", fmt.Errorf("unknown alias %s: %w", alias, ErrNotFound)
+	}
+
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == ".." || strings.ContainsRune(segment, '\\') || strings.ContainsRune(segment, 0) {
+			return "", fmt.Errorf("%s: %w", url, ErrBadPath)
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("root of %s: %w", alias, notFound(err))
+	}
+
+	name, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(path.Clean("/"+rest))))
+	if err != nil {
+		return "", notFound(err)
+	}
+
+	if relative, err := filepath.Rel(root, name); err != nil || relative == ".." ||
+		strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: %w", url, ErrBadPath)
+	}
+
+	return name, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,145 @@
+package fetcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/stretchr/testify/require"
+)
+
+func copyImage(t *testing.T, name, target string) {
+	t.Helper()
+
+	body, err := os.ReadFile("../../../resources/images/" + name)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+	require.NoError(t, os.WriteFile(target, body, 0o600))
+}
+
+func newLocal(t *testing.T, maxSize int64) (fetcher.FetchInterface, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+
+	copyImage(t, "_gopher_original_1024x504.jpg", filepath.Join(root, "a", "gopher.jpg"))
+	copyImage(t, "_gopher_original_1024x504.webp", filepath.Join(root, "gopher.webp"))
+	copyImage(t, "_gopher_original_1024x504.jpg", filepath.Join(dir, "secret.jpg"))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret.jpg"), filepath.Join(root, "escape.jpg")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "a", "gopher.jpg"), filepath.Join(root, "inside.jpg")))
+
+	content := sniff.New([]string{"image/jpeg", "image/png"}, false)
+
+	return fetcher.NewLocalFetcher("", map[string]string{"img": root}, content, maxSize), root
+}
+
+func TestLocalImpl_Get(t *testing.T) {
+	fetch, _ := newLocal(t, 0)
+
+	testCases := []struct {
+		url string
+		err error
+	}{
+		{"local/img/a/gopher.jpg", nil},
+		{"/local/img/a/gopher.jpg", nil},
+		{"local/img/a//./gopher.jpg", nil},
+		{"local/img/inside.jpg", nil},
+		{"LOCAL/img/a/gopher.jpg", nil},
+		{"other/img/a/gopher.jpg", fetcher.ErrBadPath},
+		{"local/img/a/../a/gopher.jpg", fetcher.ErrBadPath},
+		{"local/img/../secret.jpg", fetcher.ErrBadPath},
+		{"local/img/..%2fsecret.jpg", fetcher.ErrNotFound},
+		{`local/img/..\secret.jpg`, fetcher.ErrBadPath},
+		{"local/img/escape.jpg", fetcher.ErrBadPath},
+		{"local/img/", fetcher.ErrBadPath},
+		{"local/img", fetcher.ErrBadPath},
+		{"local/other/a/gopher.jpg", fetcher.ErrNotFound},
+		{"local/img/missing.jpg", fetcher.ErrNotFound},
+		{"local/img/a", fetcher.ErrNotFound},
+		{"local/img/gopher.webp", fetcher.ErrNotSupportedContentType},
+	}
+
+	for _, testCase := range testCases {
+		body, err := fetch.Get(context.Background(), testCase.url, nil)
+		if testCase.err != nil {
+			require.ErrorIs(t, err, testCase.err, testCase.url)
+			continue
+		}
+
+		require.NoError(t, err, testCase.url)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+	}
+}
+
+// TestLocalImpl_Router the local backend accepts every url the router sends to it.
+func TestLocalImpl_Router(t *testing.T) {
+	dir := t.TempDir()
+	copyImage(t, "_gopher_original_1024x504.jpg", filepath.Join(dir, "a", "gopher.jpg"))
+
+	roots := map[string]string{"img": dir}
+	content := sniff.New([]string{"image/jpeg"}, false)
+
+	router, err := fetcher.NewRouter([]fetcher.Route{
+		{Name: "web", Prefix: "", Fetch: named("web")},
+		{Name: "files", Prefix: "file://", Fetch: fetcher.NewLocalFetcher("file://", roots, content, 0)},
+		{Name: "archive", Prefix: "Archive/", Fetch: fetcher.NewLocalFetcher("Archive/", roots, content, 0)},
+	})
+	require.NoError(t, err)
+
+	for _, url := range []string{
+		"file://img/a/gopher.jpg",
+		"file:/img/a/gopher.jpg",
+		"FILE:/img/a/gopher.jpg",
+		"/archive/img/a/gopher.jpg",
+		"ARCHIVE/img/a/gopher.jpg",
+	} {
+		body, err := router.Get(context.Background(), url, nil)
+		require.NoError(t, err, url)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body), url)
+	}
+}
+
+// TestLocalImpl_Query the query of the request is not a part of the file name.
+func TestLocalImpl_Query(t *testing.T) {
+	fetch, _ := newLocal(t, 0)
+
+	body, err := fetch.Get(fetcher.WithQuery(context.Background(), "v=3"), "local/img/a/gopher.jpg", nil)
+	require.NoError(t, err)
+	require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+}
+
+func TestLocalImpl_MaxSize(t *testing.T) {
+	fetch, _ := newLocal(t, 1024)
+
+	_, err := fetch.Get(context.Background(), "local/img/a/gopher.jpg", nil)
+	require.ErrorIs(t, err, fetcher.ErrTooLarge)
+}
+
+func TestLocalImpl_Validator(t *testing.T) {
+	fetch, root := newLocal(t, 0)
+	validator, ok := fetch.(fetcher.ValidatorInterface)
+	require.True(t, ok)
+
+	first, err := validator.Validator(context.Background(), "local/img/a/gopher.jpg")
+	require.NoError(t, err)
+
+	same, err := validator.Validator(context.Background(), "local/img/a/gopher.jpg")
+	require.NoError(t, err)
+	require.Equal(t, first, same)
+
+	modified := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a", "gopher.jpg"), modified, modified))
+
+	changed, err := validator.Validator(context.Background(), "local/img/a/gopher.jpg")
+	require.NoError(t, err)
+	require.NotEqual(t, first, changed)
+
+	_, err = validator.Validator(context.Background(), "local/img/../secret.jpg")
+	require.ErrorIs(t, err, fetcher.ErrBadPath)
+}
@@ -0,0 +1,18 @@
+package fetcher
+
+import "context"
+
+type queryKey struct{}
+
+// WithQuery the query of the /fill request, only the http sources pass it to the origin.
+func WithQuery(ctx context.Context, rawQuery string) context.Context {
+	return context.WithValue(ctx, queryKey{}, rawQuery)
+}
+
+func QueryFromContext(ctx context.Context) string {
+	if rawQuery, ok := ctx.Value(queryKey{}).(string); ok {
+		return rawQuery
+	}
+
+	return ""
+}
@@ -0,0 +1,104 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+)
+
+var (
+	ErrNotSupportedScheme = errors.New("scheme is not supported")
+	ErrBadRoute           = errors.New("bad source route")
+)
+
+// Route sends the source urls starting with Prefix to Fetch, the empty prefix matches any url.
+type Route struct {
+	Name   string
+	Prefix string
+	Fetch  FetchInterface
+}
+
+type router struct {
+	routes []Route
+}
+
+// NewRouter the longest matching prefix wins. The prefixes are matched case-insensitively,
+// "scheme://" matches "scheme:/" as well, since the route of the service cleans the double slash.
+func NewRouter(routes []Route) (FetchInterface, error) {
+	seen := make(map[string]string, len(routes))
+	sorted := make([]Route, 0, len(routes))
+
+	for _, route := range routes {
+		prefix := normalize(route.Prefix)
+		if name, ok := seen[prefix]; ok {
+			return nil, fmt.Errorf("prefix %q of %s is taken by %s: %w", route.Prefix, route.Name, name, ErrBadRoute)
+		}
+
+		seen[prefix] = route.Name
+		route.Prefix = prefix
+		sorted = append(sorted, route)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].Prefix) > len(sorted[j].Prefix)
+	})
+
+	return &router{routes: sorted}, nil
+}
+
+func (r *router) route(url string) (Route, error) {
+	normalized := normalize(url)
+	for _, route := range r.routes {
+		if strings.HasPrefix(normalized, route.Prefix) {
+			return route, nil
+		}
+	}
+
+	return Route{}, fmt.Errorf("%s: %w", url, ErrNotSupportedScheme)
+}
+
+func (r *router) Get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	route, err := r.route(url)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, logger.Nop()).Debug("source routed", "url", url, "backend", route.Name)
+
+	return route.Fetch.Get(ctx, url, header)
+}
+
+// Validator of the backend, the backends without validators have none.
+func (r *router) Validator(ctx context.Context, url string) (string, error) {
+	route, err := r.route(url)
+	if err != nil {
+		return "", err
+	}
+
+	if validator, ok := route.Fetch.(ValidatorInterface); ok {
+		return validator.Validator(ctx, url)
+	}
+
+	return "", nil
+}
+
+func normalize(url string) string {
+	return strings.ToLower(strings.Replace(strings.TrimPrefix(url, "/"), "://", ":/", 1))
+}
+
+// trimPrefix removes the prefix matched the way NewRouter does, so the backend accepts every url routed to it.
+func trimPrefix(url string, prefix string) (string, bool) {
+	url = strings.Replace(strings.TrimPrefix(url, "/"), "://", ":/", 1)
+	prefix = normalize(prefix)
+
+	if len(url) < len(prefix) || !strings.EqualFold(url[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return url[len(prefix):], true
+}
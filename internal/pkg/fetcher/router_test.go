@@ -0,0 +1,80 @@
+package fetcher_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/stretchr/testify/require"
+)
+
+type named string
+
+func (n named) Get(context.Context, string, http.Header) ([]byte, error) {
+	return []byte(n), nil
+}
+
+type validated struct {
+	named
+}
+
+func (v validated) Validator(context.Context, string) (string, error) {
+	return "v1", nil
+}
+
+func TestRouter(t *testing.T) {
+	router, err := fetcher.NewRouter([]fetcher.Route{
+		{Name: "web", Prefix: "", Fetch: named("web")},
+		{Name: "bucket", Prefix: "s3://", Fetch: named("bucket")},
+		{Name: "archive", Prefix: "s3://archive/", Fetch: named("archive")},
+		{Name: "local", Prefix: "local/", Fetch: validated{named("local")}},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{"example.com/a.jpg", "web"},
+		{"https://example.com/a.jpg", "web"},
+		{"s3://images/a.jpg", "bucket"},
+		{"s3:/images/a.jpg", "bucket"},
+		{"S3://images/a.jpg", "bucket"},
+		{"s3://archive/a.jpg", "archive"},
+		{"s3:/archive/a.jpg", "archive"},
+		{"/local/img/a.jpg", "local"},
+		{"localhost/a.jpg", "web"},
+	}
+
+	for _, testCase := range testCases {
+		body, err := router.Get(context.Background(), testCase.url, nil)
+		require.NoError(t, err, testCase.url)
+		require.Equal(t, testCase.expected, string(body), testCase.url)
+	}
+
+	validator, ok := router.(fetcher.ValidatorInterface)
+	require.True(t, ok)
+
+	value, err := validator.Validator(context.Background(), "local/img/a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "v1", value)
+
+	value, err = validator.Validator(context.Background(), "example.com/a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "", value)
+}
+
+func TestRouter_Errors(t *testing.T) {
+	_, err := fetcher.NewRouter([]fetcher.Route{
+		{Name: "a", Prefix: "s3://", Fetch: named("a")},
+		{Name: "b", Prefix: "S3:/", Fetch: named("b")},
+	})
+	require.ErrorIs(t, err, fetcher.ErrBadRoute)
+
+	router, err := fetcher.NewRouter([]fetcher.Route{{Name: "a", Prefix: "s3://", Fetch: named("a")}})
+	require.NoError(t, err)
+
+	_, err = router.Get(context.Background(), "example.com/a.jpg", nil)
+	require.ErrorIs(t, err, fetcher.ErrNotSupportedScheme)
+}
@@ -0,0 +1,144 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/sigv4"
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+)
+
+const S3Scheme = "s3:"
+
+var (
+	ErrSourceError = errors.New("source responded with an error")
+	ErrNoBuckets   = errors.New("no buckets allowed")
+)
+
+type S3Config struct {
+	// Endpoint of the storage, e.g. https://s3.eu-central-1.amazonaws.com. The buckets are addressed by path.
+	Endpoint string
+	Region   string
+
+	// Buckets the only buckets served, the credentials may reach the others.
+	Buckets []string
+
+	Credentials sigv4.Credentials
+}
+
+// NewS3Fetcher gets the objects of s3://bucket/key with the signed requests.
+func NewS3Fetcher(
+	config S3Config,
+	transport http.RoundTripper,
+	timeout time.Duration,
+	content sniff.CheckerInterface,
+	maxSize int64,
+) (FetchInterface, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("s3 endpoint: %w", err)
+	}
+
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("s3 endpoint %q: %w", config.Endpoint, ErrNotSupportedScheme)
+	}
+
+	if len(config.Buckets) == 0 {
+		return nil, fmt.Errorf("s3: %w", ErrNoBuckets)
+	}
+
+	buckets := make(map[string]struct{}, len(config.Buckets))
+	for _, bucket := range config.Buckets {
+		buckets[bucket] = struct{}{}
+	}
+
+	return &s3Impl{
+		endpoint: endpoint,
+		buckets:  buckets,
+		config:   config,
+		client:   &http.Client{Transport: transport, Timeout: timeout},
+		content:  content,
+		maxSize:  maxSize,
+		now:      time.Now,
+	}, nil
+}
+
+type s3Impl struct {
+	endpoint *url.URL
+	buckets  map[string]struct{}
+	config   S3Config
+	client   *http.Client
+	content  sniff.CheckerInterface
+	maxSize  int64
+	now      func() time.Time
+}
+
+func (f *s3Impl) Get(ctx context.Context, rawURL string, _ http.Header) ([]byte, error) {
+	request, err := f.prepare(ctx, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare request: %w", err)
+	}
+
+	resp, err := f.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete the request: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", rawURL, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: %w", resp.Status, ErrSourceError)
+	}
+
+	body, err := sizelimit.ReadAll(resp.Body, resp.ContentLength, f.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	if _, err := f.content.Check(resp.Header.Get("Content-Type"), body); err != nil {
+		return nil, fmt.Errorf("unexpected content: %w", err)
+	}
+
+	return body, nil
+}
+
+// prepare accepts s3://bucket/key and s3:/bucket/key, as the route cleans the double slash.
+func (f *s3Impl) prepare(ctx context.Context, rawURL string) (*http.Request, error) {
+	object := strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(rawURL, "/"), S3Scheme), "/")
+
+	bucket, key, ok := strings.Cut(object, "/")
+	if !ok || bucket == "" || key == "" {
+		return nil, fmt.Errorf("%s: %w", rawURL, ErrBadPath)
+	}
+
+	if _, ok := f.buckets[bucket]; !ok {
+		return nil, fmt.Errorf("bucket %s: %w", bucket, ErrBadPath)
+	}
+
+	target := *f.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + bucket + "/" + key
+	target.RawPath = ""
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	sigv4.Sign(request, f.config.Credentials, f.config.Region, "s3", f.now())
+
+	logger.FromContext(ctx, logger.Nop()).Debug("fetching object", "bucket", bucket, "key", key)
+
+	return request, nil
+}
@@ -0,0 +1,168 @@
+package fetcher_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/sigv4"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/stretchr/testify/require"
+)
+
+var s3Credentials = sigv4.Credentials{AccessKey: "AKID", SecretKey: "secret"}
+
+// s3Server a stand-in of the storage: checks the signature and serves the objects by /bucket/key.
+func s3Server(t *testing.T, objects map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validSignature(r) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code></Error>"))
+			return
+		}
+
+		body, ok := objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("<Error><Code>NoSuchKey</Code></Error>"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "binary/octet-stream")
+		_, _ = w.Write(body)
+	}))
+}
+
+// validSignature signs the same request again with the headers the client signed.
+func validSignature(r *http.Request) bool {
+	authorization := r.Header.Get("Authorization")
+
+	_, signed, ok := strings.Cut(authorization, "SignedHeaders=")
+	if !ok {
+		return false
+	}
+
+	signed, _, _ = strings.Cut(signed, ",")
+
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		return false
+	}
+
+	for _, name := range strings.Split(signed, ";") {
+		if name != "host" {
+			req.Header.Set(name, r.Header.Get(name))
+		}
+	}
+
+	sigv4.Sign(req, s3Credentials, "us-east-1", "s3", now)
+
+	return req.Header.Get("Authorization") == authorization
+}
+
+func newS3(t *testing.T, endpoint string, credentials sigv4.Credentials, maxSize int64) fetcher.FetchInterface {
+	t.Helper()
+
+	fetch, err := fetcher.NewS3Fetcher(fetcher.S3Config{
+		Endpoint:    endpoint,
+		Region:      "us-east-1",
+		Buckets:     []string{"images", "other"},
+		Credentials: credentials,
+	}, &http.Transport{}, time.Second, sniff.New([]string{"image/jpeg"}, false), maxSize)
+	require.NoError(t, err)
+
+	return fetch
+}
+
+func TestS3Impl_Get(t *testing.T) {
+	jpeg, err := os.ReadFile("../../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+
+	webp, err := os.ReadFile("../../../resources/images/_gopher_original_1024x504.webp")
+	require.NoError(t, err)
+
+	server := s3Server(t, map[string][]byte{
+		"/images/gopher.jpg":        jpeg,
+		"/images/dir/a b+ü.jpg":     jpeg,
+		"/images/gopher.webp":       webp,
+		"/other/deep/path/file.jpg": jpeg,
+		"/private/gopher.jpg":       jpeg,
+	})
+	defer server.Close()
+
+	fetch := newS3(t, server.URL, s3Credentials, 0)
+
+	testCases := []struct {
+		url string
+		err error
+	}{
+		{"s3://images/gopher.jpg", nil},
+		{"s3:/images/gopher.jpg", nil},
+		{"/s3:/images/gopher.jpg", nil},
+		{"s3://images/dir/a b+ü.jpg", nil},
+		{"s3://other/deep/path/file.jpg", nil},
+		{"s3://images/missing.jpg", fetcher.ErrNotFound},
+		{"s3://images/gopher.webp", fetcher.ErrNotSupportedContentType},
+		{"s3://images", fetcher.ErrBadPath},
+		{"s3://images/", fetcher.ErrBadPath},
+		{"s3://private/gopher.jpg", fetcher.ErrBadPath},
+		{"s3://Images/gopher.jpg", fetcher.ErrBadPath},
+		{"s3://imagesx/gopher.jpg", fetcher.ErrBadPath},
+	}
+
+	for _, testCase := range testCases {
+		body, err := fetch.Get(context.Background(), testCase.url, nil)
+		if testCase.err != nil {
+			require.ErrorIs(t, err, testCase.err, testCase.url)
+			continue
+		}
+
+		require.NoError(t, err, testCase.url)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body), testCase.url)
+	}
+
+	t.Run("query", func(t *testing.T) {
+		body, err := fetch.Get(fetcher.WithQuery(context.Background(), "v=3"), "s3://images/gopher.jpg", nil)
+		require.NoError(t, err)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		fetch := newS3(t, server.URL, sigv4.Credentials{AccessKey: "AKID", SecretKey: "wrong"}, 0)
+
+		_, err := fetch.Get(context.Background(), "s3://images/gopher.jpg", nil)
+		require.ErrorIs(t, err, fetcher.ErrSourceError)
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		fetch := newS3(t, server.URL, s3Credentials, 1024)
+
+		_, err := fetch.Get(context.Background(), "s3://images/gopher.jpg", nil)
+		require.ErrorIs(t, err, fetcher.ErrTooLarge)
+	})
+
+	t.Run("endpoint", func(t *testing.T) {
+		_, err := fetcher.NewS3Fetcher(fetcher.S3Config{Endpoint: "ftp://storage", Buckets: []string{"images"}},
+			&http.Transport{}, time.Second, sniff.New(nil, false), 0)
+		require.ErrorIs(t, err, fetcher.ErrNotSupportedScheme)
+	})
+
+	t.Run("no buckets", func(t *testing.T) {
+		_, err := fetcher.NewS3Fetcher(fetcher.S3Config{Endpoint: server.URL}, &http.Transport{}, time.Second,
+			sniff.New(nil, false), 0)
+		require.ErrorIs(t, err, fetcher.ErrNoBuckets)
+	})
+}
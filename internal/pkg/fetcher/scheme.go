@@ -0,0 +1,78 @@
+package fetcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+const DefaultScheme = "https"
+
+// SchemeRule the hosts that are fetched with the scheme, unless the source url has its own.
+type SchemeRule struct {
+	Hosts  hostmatch.MatcherInterface
+	Scheme string
+}
+
+// SchemeInterface chooses the scheme of the source urls without one.
+type SchemeInterface interface {
+	Scheme(host string) string
+}
+
+type schemes struct {
+	fallback string
+	rules    []SchemeRule
+}
+
+// NewSchemes the first matching rule wins, the empty fallback means DefaultScheme.
+func NewSchemes(fallback string, rules []SchemeRule) (SchemeInterface, error) {
+	if fallback == "" {
+		fallback = DefaultScheme
+	}
+
+	if err := checkScheme(fallback); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		if err := checkScheme(rule.Scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	return &schemes{fallback: strings.ToLower(fallback), rules: rules}, nil
+}
+
+func (s *schemes) Scheme(host string) string {
+	for _, rule := range s.rules {
+		if rule.Hosts.Match(host) {
+			return strings.ToLower(rule.Scheme)
+		}
+	}
+
+	return s.fallback
+}
+
+func checkScheme(scheme string) error {
+	if scheme := strings.ToLower(scheme); scheme != "http" && scheme != "https" {
+		return fmt.Errorf("%q: %w", scheme, ErrNotSupportedScheme)
+	}
+
+	return nil
+}
+
+// splitScheme the explicit scheme of the source url: https://host/path, https:/host/path as the route cleans
+// the double slash, or the scheme segment https/host/path.
+func splitScheme(rawURL string) (string, string) {
+	for _, scheme := range []string{"https", "http"} {
+		for _, separator := range []string{"://", ":/", "/"} {
+			prefix := scheme + separator
+			if len(rawURL) > len(prefix) && strings.EqualFold(rawURL[:len(prefix)], prefix) {
+				return scheme, rawURL[len(prefix):]
+			}
+		}
+	}
+
+	return "", rawURL
+}
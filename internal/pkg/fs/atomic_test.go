@@ -0,0 +1,179 @@
+package fs_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/stretchr/testify/require"
+)
+
+var errFault = errors.New("injected fault")
+
+func TestCreate_Faults(t *testing.T) {
+	cases := []struct {
+		name   string
+		faults fs.Faults
+		err    error
+	}{
+		{name: "write", faults: fs.Faults{Write: errFault}, err: fs.ErrWriteFile},
+		{name: "sync", faults: fs.Faults{Sync: errFault}, err: fs.ErrWriteFile},
+		{name: "close", faults: fs.Faults{Close: errFault}, err: fs.ErrCloseFile},
+		{name: "rename", faults: fs.Faults{Rename: errFault}, err: fs.ErrCreateFile},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, fs.New(dir, "test").Create("hello", []byte("old content")))
+
+			fm := fs.NewFaulty(dir, "test", &c.faults)
+			require.ErrorIs(t, fm.Create("hello", []byte("new content")), c.err)
+			require.Equal(t, 1, c.faults.Closed, "the temporary file is closed")
+
+			// the old content survives and no temporary file is left behind
+			cnt, err := fm.Content("hello")
+			require.NoError(t, err)
+			require.Equal(t, []byte("old content"), cnt)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestNew_RemovesTemp(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, fs.New(dir, "test").Create("hello", []byte("hello world")))
+
+	// the leftovers of a crash between the write and the rename
+	stale := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"test-hello.tmp-123", "test-other.tmp-456", "another-hello.tmp-789"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("partial"), 0o600))
+		require.NoError(t, os.Chtimes(path, stale, stale))
+	}
+
+	// the write in progress of another process
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test-live.tmp-999"), []byte("partial"), 0o600))
+
+	fm := fs.New(dir, "test")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	// the files of the other prefix and the fresh temporary files are not touched
+	require.Equal(t, []string{"another-hello.tmp-789", "test-hello", "test-live.tmp-999"}, names)
+
+	cnt, err := fm.Content("hello")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello world"), cnt)
+}
+
+func TestContent_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	fm := fs.New(dir, "test")
+	require.NoError(t, fm.Create("hello", []byte("hello world")))
+
+	path := filepath.Join(dir, "test-hello")
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	t.Run("corrupted", func(t *testing.T) {
+		corrupted := append([]byte(nil), raw...)
+		corrupted[len(corrupted)-1] ^= 1
+		require.NoError(t, os.WriteFile(path, corrupted, 0o600))
+
+		cnt, err := fm.Content("hello")
+		require.ErrorIs(t, err, fs.ErrChecksum)
+		require.Nil(t, cnt)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, size := range []int{0, 5, len(raw) - 1} {
+			require.NoError(t, os.WriteFile(path, raw[:size], 0o600))
+
+			_, err := fm.Content("hello")
+			require.ErrorIs(t, err, fs.ErrChecksum, size)
+		}
+	})
+
+	t.Run("empty content", func(t *testing.T) {
+		require.NoError(t, fm.Create("empty", nil))
+
+		cnt, err := fm.Content("empty")
+		require.NoError(t, err)
+		require.Empty(t, cnt)
+	})
+}
+
+// TestContent_Concurrent the readers see one of the written versions in full while it is rewritten.
+func TestContent_Concurrent(t *testing.T) {
+	fm := fs.New(t.TempDir(), "test")
+
+	versions := [][]byte{
+		bytes.Repeat([]byte("a"), 1<<16),
+		bytes.Repeat([]byte("b"), 1<<17),
+	}
+	require.NoError(t, fm.Create("hello", versions[0]))
+
+	var (
+		wg      sync.WaitGroup
+		partial atomic.Int32
+	)
+
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 50; i++ {
+			if err := fm.Create("hello", versions[i%2]); err != nil {
+				errs <- err
+
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				cnt, err := fm.Content("hello")
+				if err != nil || (!bytes.Equal(cnt, versions[0]) && !bytes.Equal(cnt, versions[1])) {
+					partial.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	require.NoError(t, <-errs)
+	require.Zero(t, partial.Load())
+}
@@ -0,0 +1,66 @@
+package fs
+
+import "os"
+
+// Faults the errors injected into the writes, the failed write leaves a half of the buffer in the file.
+type Faults struct {
+	Write, Sync, Close, Rename error
+
+	// Closed counts the closed temporary files.
+	Closed int
+}
+
+type faultyFile struct {
+	*os.File
+
+	faults *Faults
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.faults.Write != nil {
+		n, _ := f.File.Write(p[:len(p)/2])
+
+		return n, f.faults.Write
+	}
+
+	return f.File.Write(p)
+}
+
+func (f *faultyFile) Sync() error {
+	if f.faults.Sync != nil {
+		return f.faults.Sync
+	}
+
+	return f.File.Sync()
+}
+
+func (f *faultyFile) Close() error {
+	f.faults.Closed++
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+
+	return f.faults.Close
+}
+
+// NewFaulty the file storage of New with the faults injected into its writes.
+func NewFaulty(dir string, prefix string, faults *Faults) FileInterface {
+	f := New(dir, prefix).(*impl)
+	f.createTemp = func(dir, pattern string) (file, error) {
+		tmp, err := os.CreateTemp(dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		return &faultyFile{File: tmp, faults: faults}, nil
+	}
+	f.rename = func(oldPath, newPath string) error {
+		if faults.Rename != nil {
+			return faults.Rename
+		}
+
+		return os.Rename(oldPath, newPath)
+	}
+
+	return f
+}
@@ -0,0 +1,58 @@
+package fs
+
+import "github.com/rez1dent3/otus-final/internal/pkg/lru"
+
+// NewTiered keeps the recently used files in memory in front of the disk. The memory cache is the hot tier
+// with its own limit: a file read from the disk is promoted to it, a deleted file leaves both tiers.
+// The content of the hot tier is shared, the callers must not modify it.
+func NewTiered(disk FileInterface, memory lru.CacheInterface) FileInterface {
+	return &tiered{disk: disk, memory: memory}
+}
+
+type tiered struct {
+	disk   FileInterface
+	memory lru.CacheInterface
+}
+
+type memoryItem struct {
+	content []byte
+}
+
+func (i memoryItem) Size() uint64 {
+	return uint64(len(i.content))
+}
+
+func (t *tiered) Create(name string, content []byte) error {
+	if err := t.disk.Create(name, content); err != nil {
+		t.memory.Remove(name)
+
+		return err
+	}
+
+	t.memory.Put(name, memoryItem{content: content})
+
+	return nil
+}
+
+func (t *tiered) Content(name string) ([]byte, error) {
+	if val, ok := t.memory.Get(name); ok {
+		if item, ok := val.(memoryItem); ok {
+			return item.content, nil
+		}
+	}
+
+	content, err := t.disk.Content(name)
+	if err != nil {
+		return nil, err
+	}
+
+	t.memory.Put(name, memoryItem{content: content})
+
+	return content, nil
+}
+
+func (t *tiered) Delete(name string) error {
+	t.memory.Remove(name)
+
+	return t.disk.Delete(name)
+}
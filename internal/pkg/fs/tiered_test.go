@@ -0,0 +1,129 @@
+package fs_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/stretchr/testify/require"
+)
+
+type countingFile struct {
+	fs.FileInterface
+	reads int
+}
+
+func (c *countingFile) Content(name string) ([]byte, error) {
+	c.reads++
+
+	return c.FileInterface.Content(name)
+}
+
+func TestTiered(t *testing.T) {
+	t.Run("promotion", func(t *testing.T) {
+		disk := &countingFile{FileInterface: fs.New(t.TempDir(), "test")}
+		memory := lru.New(16, bus.NewSyncBus())
+		fm := fs.NewTiered(disk, memory)
+
+		// written by another instance, so the hot tier is cold
+		require.NoError(t, disk.Create("hello", []byte("hello world")))
+
+		for i := 0; i < 3; i++ {
+			cnt, err := fm.Content("hello")
+			require.NoError(t, err)
+			require.Equal(t, []byte("hello world"), cnt)
+		}
+
+		require.Equal(t, 1, disk.reads)
+		require.True(t, memory.Has("hello"))
+	})
+
+	t.Run("write through", func(t *testing.T) {
+		disk := &countingFile{FileInterface: fs.New(t.TempDir(), "test")}
+		fm := fs.NewTiered(disk, lru.New(16, bus.NewSyncBus()))
+
+		require.NoError(t, fm.Create("hello", []byte("hello world")))
+		require.NoError(t, fm.Create("hello", []byte("hello")))
+
+		cnt, err := fm.Content("hello")
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), cnt)
+		require.Equal(t, 0, disk.reads)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		memory := lru.New(16, bus.NewSyncBus())
+		fm := fs.NewTiered(fs.New(t.TempDir(), "test"), memory)
+
+		require.NoError(t, fm.Create("hello", []byte("hello world")))
+		require.NoError(t, fm.Delete("hello"))
+		require.False(t, memory.Has("hello"))
+
+		_, err := fm.Content("hello")
+		require.ErrorIs(t, err, fs.ErrOpenFile)
+	})
+
+	t.Run("hot tier eviction keeps the disk", func(t *testing.T) {
+		disk := &countingFile{FileInterface: fs.New(t.TempDir(), "test")}
+		memory := lru.New(16, bus.NewSyncBus())
+		fm := fs.NewTiered(disk, memory)
+
+		require.NoError(t, fm.Create("a", []byte("0123456789")))
+		require.NoError(t, fm.Create("b", []byte("0123456789")))
+		require.False(t, memory.Has("a"))
+
+		cnt, err := fm.Content("a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("0123456789"), cnt)
+		require.Equal(t, 1, disk.reads)
+	})
+
+	t.Run("larger than the hot tier", func(t *testing.T) {
+		memory := lru.New(4, bus.NewSyncBus())
+		fm := fs.NewTiered(fs.New(t.TempDir(), "test"), memory)
+
+		require.NoError(t, fm.Create("hello", []byte("hello world")))
+		require.False(t, memory.Has("hello"))
+
+		cnt, err := fm.Content("hello")
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello world"), cnt)
+	})
+
+	t.Run("failed create", func(t *testing.T) {
+		memory := lru.New(16, bus.NewSyncBus())
+		fm := fs.NewTiered(fs.New(t.TempDir()+"/missing", "test"), memory)
+
+		require.ErrorIs(t, fm.Create("hello", []byte("hello")), fs.ErrCreateFile)
+		require.False(t, memory.Has("hello"))
+	})
+}
+
+// BenchmarkContent the hit latency of a 64K preview, from the disk and from the hot tier.
+func BenchmarkContent(b *testing.B) {
+	content := make([]byte, 64<<10)
+	_, _ = rand.Read(content)
+
+	cases := map[string]fs.FileInterface{
+		"disk":   fs.New(b.TempDir(), "bench"),
+		"memory": fs.NewTiered(fs.New(b.TempDir(), "bench"), lru.New(1<<20, bus.NewSyncBus())),
+	}
+
+	for name, fm := range cases {
+		fm := fm
+		require.NoError(b, fm.Create("preview", content))
+
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := fm.Content("preview"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
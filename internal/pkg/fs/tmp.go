@@ -1,10 +1,14 @@
 package fs
 
 import (
+	"encoding/binary"
 	"errors"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
@@ -14,61 +18,183 @@ var (
 	ErrWriteFile  = errors.New("failed to write to file")
 	ErrDeleteFile = errors.New("failed to delete file")
 	ErrCloseFile  = errors.New("failed to close file")
+	ErrChecksum   = errors.New("file checksum mismatch")
 )
 
+// header the magic, the crc32c of the content and its length precede the content of every file.
+const (
+	magic      = "imgp"
+	headerSize = len(magic) + 4 + 8
+)
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+// tempSuffix marks the temporary files of Create.
+const tempSuffix = ".tmp-"
+
+// staleTemp a temporary file older than this is a leftover, a younger one may belong to another process
+// writing to the same directory.
+const staleTemp = time.Hour
+
 type FileInterface interface {
 	Create(string, []byte) error
 	Content(string) ([]byte, error)
 	Delete(string) error
 }
 
+// file the part of *os.File the writes need.
+type file interface {
+	io.Writer
+	Sync() error
+	Close() error
+	Name() string
+}
+
+// New the temporary files left by a crash are removed.
 func New(dir string, prefix string) FileInterface {
-	return &impl{dir: dir, prefix: prefix}
+	removeTemp(dir, prefix)
+
+	return &impl{
+		dir:    dir,
+		prefix: prefix,
+		createTemp: func(dir, pattern string) (file, error) {
+			return os.CreateTemp(dir, pattern)
+		},
+		rename: os.Rename,
+	}
 }
 
 type impl struct {
 	dir    string
 	prefix string
+
+	createTemp func(dir, pattern string) (file, error)
+	rename     func(oldPath, newPath string) error
 }
 
 func (f *impl) path(name string) string {
 	return filepath.Join(f.dir, f.prefix+"-"+name)
 }
 
+// tempPattern the name of the temporary file of Create, the random part replaces the star.
+func (f *impl) tempPattern(name string) string {
+	return f.prefix + "-" + name + tempSuffix + "*"
+}
+
+// removeTemp the stale temporary files are the leftovers of the interrupted writes.
+func removeTemp(dir string, prefix string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	deadline := time.Now().Add(-staleTemp)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !strings.HasPrefix(name, prefix+"-") || !strings.Contains(name, tempSuffix) {
+			continue
+		}
+
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(deadline) {
+			_ = os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
+
+// Create writes a temporary file, syncs it and renames it over the old one, so a reader sees either the old
+// content or the new one.
 func (f *impl) Create(name string, content []byte) error {
-	file, err := os.Create(f.path(name))
+	tmp, err := f.createTemp(f.dir, f.tempPattern(name))
 	if err != nil {
 		return ErrCreateFile
 	}
 
-	_, err = file.Write(content)
-	if err != nil {
+	if err := f.write(tmp, content); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := f.rename(tmp.Name(), f.path(name)); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return ErrCreateFile
+	}
+
+	syncDir(f.dir)
+
+	return nil
+}
+
+// write the file is closed in any case.
+func (f *impl) write(tmp file, content []byte) error {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], crc32.Checksum(content, table))
+	binary.BigEndian.PutUint64(header[len(magic)+4:], uint64(len(content)))
+
+	if _, err := tmp.Write(header); err != nil {
+		_ = tmp.Close()
+
+		return ErrWriteFile
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+
 		return ErrWriteFile
 	}
 
-	if err := file.Close(); err != nil {
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return ErrWriteFile
+	}
+
+	if err := tmp.Close(); err != nil {
 		return ErrCloseFile
 	}
 
 	return nil
 }
 
+// syncDir makes the rename durable, the file systems without the directory sync are fine without it.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
+
+// Content the checksum is verified, a truncated or damaged file is ErrChecksum.
 func (f *impl) Content(name string) ([]byte, error) {
 	file, err := os.Open(f.path(name))
 	if err != nil {
 		return nil, ErrOpenFile
 	}
 
+	defer func() {
+		_ = file.Close()
+	}()
+
 	readAll, err := io.ReadAll(file)
 	if err != nil {
 		return nil, ErrReadFile
 	}
 
-	if err := file.Close(); err != nil {
-		return nil, ErrCloseFile
+	if len(readAll) < headerSize || string(readAll[:len(magic)]) != magic {
+		return nil, ErrChecksum
+	}
+
+	checksum := binary.BigEndian.Uint32(readAll[len(magic):])
+	length := binary.BigEndian.Uint64(readAll[len(magic)+4:])
+	content := readAll[headerSize:]
+
+	if uint64(len(content)) != length || crc32.Checksum(content, table) != checksum {
+		return nil, ErrChecksum
 	}
 
-	return readAll, nil
+	return content, nil
 }
 
 func (f *impl) Delete(name string) error {
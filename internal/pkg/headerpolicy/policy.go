@@ -0,0 +1,193 @@
+package headerpolicy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+// hopByHop the headers of a single connection (RFC 7230, section 6.1), never forwarded.
+var hopByHop = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// credentials of the client, forwarded only to the hosts of a rule that names them in Forward.
+var credentials = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+}
+
+// owned by the fetcher: the origin must answer with the whole plain image, not a part or 304.
+var owned = []string{
+	"Host",
+	"Content-Length",
+	"Accept-Encoding",
+	"Range",
+	"If-Range",
+	"If-Match",
+	"If-None-Match",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+}
+
+// HostRule changes the headers of the requests to the hosts matching the patterns (see hostmatch).
+type HostRule struct {
+	Hosts []string
+
+	// Forward the headers of the client forwarded to these hosts despite Allowed, Denied and the credentials.
+	Forward []string
+
+	Set    map[string]string
+	Remove []string
+}
+
+// Config the zero value forwards every header of the client, except the hop-by-hop ones and the credentials.
+type Config struct {
+	// Allowed if not empty, only these headers are forwarded. Denied are never forwarded.
+	Allowed []string
+	Denied  []string
+
+	// Rename the header of the client is forwarded under the new name.
+	Rename map[string]string
+
+	// Hosts the rules applied in order after the headers of the client are filtered.
+	Hosts []HostRule
+}
+
+type PolicyInterface interface {
+	// Apply returns the headers to send to the host, the client headers are not modified.
+	Apply(host string, header http.Header) http.Header
+}
+
+type rule struct {
+	hosts   hostmatch.MatcherInterface
+	forward map[string]struct{}
+	set     map[string]string
+	remove  []string
+}
+
+type impl struct {
+	allowed map[string]struct{}
+	denied  map[string]struct{}
+	never   map[string]struct{}
+	rename  map[string]string
+	rules   []rule
+}
+
+func New(config Config) (PolicyInterface, error) {
+	p := &impl{
+		allowed: set(config.Allowed),
+		denied:  set(append(append([]string{}, config.Denied...), credentials...)),
+		never:   set(append(append([]string{}, hopByHop...), owned...)),
+		rename:  make(map[string]string, len(config.Rename)),
+	}
+
+	for from, to := range config.Rename {
+		p.rename[http.CanonicalHeaderKey(from)] = http.CanonicalHeaderKey(to)
+	}
+
+	for _, hostRule := range config.Hosts {
+		hosts, err := hostmatch.New(hostRule.Hosts)
+		if err != nil {
+			return nil, err
+		}
+
+		p.rules = append(p.rules, rule{
+			hosts:   hosts,
+			forward: set(hostRule.Forward),
+			set:     hostRule.Set,
+			remove:  hostRule.Remove,
+		})
+	}
+
+	return p, nil
+}
+
+func (p *impl) Apply(host string, header http.Header) http.Header {
+	var rules []rule
+	for _, r := range p.rules {
+		if r.hosts.Match(host) {
+			rules = append(rules, r)
+		}
+	}
+
+	// the headers named in Connection are hop-by-hop as well
+	connection := make(map[string]struct{})
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			connection[http.CanonicalHeaderKey(strings.TrimSpace(name))] = struct{}{}
+		}
+	}
+
+	result := make(http.Header, len(header))
+	for name, values := range header {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := connection[name]; ok {
+			continue
+		}
+
+		if !p.forwarded(name, rules) {
+			continue
+		}
+
+		if to, ok := p.rename[name]; ok {
+			name = to
+		}
+
+		result[name] = append(result[name], values...)
+	}
+
+	for _, r := range rules {
+		for _, name := range r.remove {
+			result.Del(name)
+		}
+
+		for name, value := range r.set {
+			result.Set(name, value)
+		}
+	}
+
+	return result
+}
+
+func (p *impl) forwarded(name string, rules []rule) bool {
+	if _, ok := p.never[name]; ok {
+		return false
+	}
+
+	for _, r := range rules {
+		if _, ok := r.forward[name]; ok {
+			return true
+		}
+	}
+
+	if _, ok := p.denied[name]; ok {
+		return false
+	}
+
+	if len(p.allowed) == 0 {
+		return true
+	}
+
+	_, ok := p.allowed[name]
+
+	return ok
+}
+
+func set(names []string) map[string]struct{} {
+	result := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		result[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+
+	return result
+}
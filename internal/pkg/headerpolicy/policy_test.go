@@ -0,0 +1,125 @@
+package headerpolicy_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/headerpolicy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Apply(t *testing.T) {
+	client := func() http.Header {
+		return http.Header{
+			"Accept":              []string{"image/*"},
+			"Authorization":       []string{"Basic dXNlcjp1c2Vy"},
+			"Cookie":              []string{"session=1"},
+			"Proxy-Authorization": []string{"Basic cHJveHk6cHJveHk="},
+			"Connection":          []string{"keep-alive, X-Secret"},
+			"X-Secret":            []string{"1"},
+			"Keep-Alive":          []string{"timeout=5"},
+			"Te":                  []string{"trailers"},
+			"Upgrade":             []string{"h2c"},
+			"Accept-Encoding":     []string{"br"},
+			"If-None-Match":       []string{`"etag"`},
+			"Range":               []string{"bytes=0-10"},
+			"User-Agent":          []string{"curl"},
+			"X-Client":            []string{"mobile"},
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		policy, err := headerpolicy.New(headerpolicy.Config{})
+		require.NoError(t, err)
+
+		header := client()
+		// the credentials of the client are dropped
+		require.Equal(t, http.Header{
+			"Accept":     []string{"image/*"},
+			"User-Agent": []string{"curl"},
+			"X-Client":   []string{"mobile"},
+		}, policy.Apply("example.com", header))
+
+		// the client headers are intact
+		require.Equal(t, client(), header)
+	})
+
+	t.Run("allowed and denied", func(t *testing.T) {
+		policy, err := headerpolicy.New(headerpolicy.Config{
+			Allowed: []string{"accept", "user-agent", "cookie", "upgrade"},
+			Denied:  []string{"Cookie"},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, http.Header{
+			"Accept":     []string{"image/*"},
+			"User-Agent": []string{"curl"},
+		}, policy.Apply("example.com", client()))
+	})
+
+	t.Run("forward credentials", func(t *testing.T) {
+		policy, err := headerpolicy.New(headerpolicy.Config{
+			Allowed: []string{"Accept"},
+			Denied:  []string{"Cookie"},
+			Hosts: []headerpolicy.HostRule{
+				{Hosts: []string{"*.example.com"}, Forward: []string{"authorization", "Cookie", "Connection", "X-Secret"}},
+			},
+		})
+		require.NoError(t, err)
+
+		// the hop-by-hop headers are never forwarded
+		require.Equal(t, http.Header{
+			"Accept":        []string{"image/*"},
+			"Authorization": []string{"Basic dXNlcjp1c2Vy"},
+			"Cookie":        []string{"session=1"},
+		}, policy.Apply("cdn.example.com", client()))
+
+		require.Equal(t, http.Header{
+			"Accept": []string{"image/*"},
+		}, policy.Apply("example.org", client()))
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		policy, err := headerpolicy.New(headerpolicy.Config{
+			Allowed: []string{"X-Client"},
+			Rename:  map[string]string{"x-client": "X-Device"},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, http.Header{
+			"X-Device": []string{"mobile"},
+		}, policy.Apply("example.com", client()))
+	})
+
+	t.Run("hosts", func(t *testing.T) {
+		policy, err := headerpolicy.New(headerpolicy.Config{
+			Denied: []string{"Authorization", "Cookie"},
+			Hosts: []headerpolicy.HostRule{
+				{Hosts: []string{"*"}, Set: map[string]string{"User-Agent": "imgproxy"}},
+				{
+					Hosts:  []string{"*.example.com"},
+					Set:    map[string]string{"X-Api-Key": "secret"},
+					Remove: []string{"x-client"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, http.Header{
+			"Accept":     []string{"image/*"},
+			"User-Agent": []string{"imgproxy"},
+			"X-Api-Key":  []string{"secret"},
+		}, policy.Apply("cdn.example.com:8080", client()))
+
+		require.Equal(t, http.Header{
+			"Accept":     []string{"image/*"},
+			"User-Agent": []string{"imgproxy"},
+			"X-Client":   []string{"mobile"},
+		}, policy.Apply("other.com", client()))
+	})
+
+	t.Run("bad host pattern", func(t *testing.T) {
+		_, err := headerpolicy.New(headerpolicy.Config{Hosts: []headerpolicy.HostRule{{Hosts: []string{""}}}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,40 @@
+package hostmatch
+
+// OtherLabel the label of the hosts matching none of the patterns.
+const OtherLabel = "other"
+
+// LabelInterface maps a host to one of a fixed set of labels, e.g. for the metrics.
+type LabelInterface interface {
+	Label(host string) string
+}
+
+type label struct {
+	patterns []string
+	matchers []MatcherInterface
+}
+
+// NewLabels the label of a host is the first pattern it matches, OtherLabel if none.
+func NewLabels(patterns []string) (LabelInterface, error) {
+	l := &label{}
+	for _, pattern := range patterns {
+		matcher, err := New([]string{pattern})
+		if err != nil {
+			return nil, err
+		}
+
+		l.patterns = append(l.patterns, Normalize(pattern))
+		l.matchers = append(l.matchers, matcher)
+	}
+
+	return l, nil
+}
+
+func (l *label) Label(host string) string {
+	for i, matcher := range l.matchers {
+		if matcher.Match(host) {
+			return l.patterns[i]
+		}
+	}
+
+	return OtherLabel
+}
@@ -0,0 +1,39 @@
+package hostmatch_test
+
+import (
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabels(t *testing.T) {
+	labels, err := hostmatch.NewLabels([]string{"CDN.example.com", "*.example.com", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		host  string
+		label string
+	}{
+		{"cdn.example.com:443", "cdn.example.com"},
+		{"img.example.com", "*.example.com"},
+		{"10.1.2.3:8080", "10.0.0.0/8"},
+		{"random-1234.invalid", hostmatch.OtherLabel},
+		{"", hostmatch.OtherLabel},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.label, labels.Label(testCase.host), testCase.host)
+	}
+
+	t.Run("no patterns", func(t *testing.T) {
+		labels, err := hostmatch.NewLabels(nil)
+		require.NoError(t, err)
+		require.Equal(t, hostmatch.OtherLabel, labels.Label("example.com"))
+	})
+
+	t.Run("bad pattern", func(t *testing.T) {
+		_, err := hostmatch.NewLabels([]string{"a*b"})
+		require.ErrorIs(t, err, hostmatch.ErrPattern)
+	})
+}
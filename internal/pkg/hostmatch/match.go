@@ -0,0 +1,117 @@
+package hostmatch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+var ErrPattern = errors.New("invalid host pattern")
+
+// MatcherInterface patterns are exact hosts ("cdn.example.com"), wildcard subdomains ("*.example.com"),
+// networks in CIDR notation ("10.0.0.0/8", matched only by ip hosts) or "*" for any host.
+type MatcherInterface interface {
+	Match(host string) bool
+	MatchIP(ip net.IP) bool
+}
+
+type impl struct {
+	any       bool
+	exact     map[string]struct{}
+	suffixes  []string
+	networks  []*net.IPNet
+	addresses map[string]struct{}
+}
+
+func New(patterns []string) (MatcherInterface, error) {
+	m := &impl{exact: make(map[string]struct{}), addresses: make(map[string]struct{})}
+
+	for _, pattern := range patterns {
+		pattern = Normalize(pattern)
+
+		switch {
+		case pattern == "":
+			return nil, fmt.Errorf("empty pattern: %w", ErrPattern)
+		case pattern == "*":
+			m.any = true
+		case strings.HasPrefix(pattern, "*."):
+			m.suffixes = append(m.suffixes, pattern[1:])
+		case strings.Contains(pattern, "/"):
+			_, network, err := net.ParseCIDR(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pattern, ErrPattern)
+			}
+
+			m.networks = append(m.networks, network)
+		case strings.Contains(pattern, "*"):
+			return nil, fmt.Errorf("%s: %w", pattern, ErrPattern)
+		default:
+			if ip := net.ParseIP(pattern); ip != nil {
+				m.addresses[ip.String()] = struct{}{}
+			} else {
+				m.exact[pattern] = struct{}{}
+			}
+		}
+	}
+
+	return m, nil
+}
+
+func (m *impl) Match(host string) bool {
+	host = Normalize(host)
+	if host == "" {
+		return false
+	}
+
+	if m.any {
+		return true
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return m.MatchIP(ip)
+	}
+
+	if _, ok := m.exact[host]; ok {
+		return true
+	}
+
+	for _, suffix := range m.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchIP checks only the ip and network patterns.
+func (m *impl) MatchIP(ip net.IP) bool {
+	if m.any {
+		return true
+	}
+
+	if _, ok := m.addresses[ip.String()]; ok {
+		return true
+	}
+
+	for _, network := range m.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Normalize lowercases the host, drops the port, the ipv6 brackets and the trailing dot.
+func Normalize(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,66 @@
+package hostmatch_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	matcher, err := hostmatch.New([]string{
+		"cdn.example.com",
+		"*.media.example.com",
+		"10.0.0.0/8",
+		"fd00::/8",
+		"192.168.1.1",
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		host    string
+		matched bool
+	}{
+		{"cdn.example.com", true},
+		{"CDN.Example.com.", true},
+		{"cdn.example.com:8080", true},
+		{"example.com", false},
+		{"img.cdn.example.com", false},
+		{"a.media.example.com", true},
+		{"a.b.media.example.com", true},
+		{"media.example.com", false},
+		{"evilmedia.example.com", false},
+		{"10.1.2.3", true},
+		{"10.1.2.3:80", true},
+		{"11.1.2.3", false},
+		{"[fd00::1]:443", true},
+		{"fe80::1", false},
+		{"192.168.1.1", true},
+		{"192.168.1.2", false},
+		{"", false},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.matched, matcher.Match(testCase.host), testCase.host)
+	}
+
+	require.True(t, matcher.MatchIP(net.ParseIP("10.255.0.1")))
+	require.False(t, matcher.MatchIP(net.ParseIP("127.0.0.1")))
+}
+
+func TestMatcher_Any(t *testing.T) {
+	matcher, err := hostmatch.New([]string{"*"})
+	require.NoError(t, err)
+
+	require.True(t, matcher.Match("example.com"))
+	require.True(t, matcher.MatchIP(net.ParseIP("127.0.0.1")))
+	require.False(t, matcher.Match(""))
+}
+
+func TestMatcher_Errors(t *testing.T) {
+	for _, pattern := range []string{"", "10.0.0.0/33", "cdn.*.com", "*example.com"} {
+		_, err := hostmatch.New([]string{pattern})
+		require.ErrorIs(t, err, hostmatch.ErrPattern, pattern)
+	}
+}
@@ -0,0 +1,37 @@
+package hostmatch
+
+import "errors"
+
+var ErrNotAllowed = errors.New("source host is not allowed")
+
+// PolicyInterface denied hosts always win, an empty allow list allows any host.
+type PolicyInterface interface {
+	Allowed(host string) bool
+}
+
+type policy struct {
+	allowed, denied MatcherInterface
+	allowAll        bool
+}
+
+func NewPolicy(allowed, denied []string) (PolicyInterface, error) {
+	allowedMatcher, err := New(allowed)
+	if err != nil {
+		return nil, err
+	}
+
+	deniedMatcher, err := New(denied)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy{allowed: allowedMatcher, denied: deniedMatcher, allowAll: len(allowed) == 0}, nil
+}
+
+func (p *policy) Allowed(host string) bool {
+	if Normalize(host) == "" || p.denied.Match(host) {
+		return false
+	}
+
+	return p.allowAll || p.allowed.Match(host)
+}
@@ -0,0 +1,45 @@
+package hostmatch_test
+
+import (
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		policy, err := hostmatch.NewPolicy(nil, nil)
+		require.NoError(t, err)
+
+		require.True(t, policy.Allowed("example.com"))
+		require.False(t, policy.Allowed(""))
+	})
+
+	t.Run("allowed and denied", func(t *testing.T) {
+		policy, err := hostmatch.NewPolicy(
+			[]string{"*.example.com", "10.0.0.0/8"},
+			[]string{"private.example.com", "10.0.0.1"},
+		)
+		require.NoError(t, err)
+
+		require.True(t, policy.Allowed("cdn.example.com"))
+		require.True(t, policy.Allowed("10.0.0.2"))
+		require.False(t, policy.Allowed("private.example.com"))
+		require.False(t, policy.Allowed("10.0.0.1"))
+		require.False(t, policy.Allowed("other.com"))
+	})
+
+	t.Run("only denied", func(t *testing.T) {
+		policy, err := hostmatch.NewPolicy(nil, []string{"*.internal"})
+		require.NoError(t, err)
+
+		require.True(t, policy.Allowed("example.com"))
+		require.False(t, policy.Allowed("db.internal"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := hostmatch.NewPolicy(nil, []string{"10.0.0.0/40"})
+		require.ErrorIs(t, err, hostmatch.ErrPattern)
+	})
+}
@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"context"
+	"io"
+)
+
+type ctxKey struct{}
+
+// Nop discards everything, used when there is no logger to fall back to.
+func Nop() LogInterface {
+	return New("off", io.Discard)
+}
+
+// WithContext attaches the logger (usually with the request fields) to the context.
+func WithContext(ctx context.Context, log LogInterface) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the attached logger or the fallback.
+func FromContext(ctx context.Context, fallback LogInterface) LogInterface {
+	if log, ok := ctx.Value(ctxKey{}).(LogInterface); ok {
+		return log
+	}
+
+	return fallback
+}
@@ -0,0 +1,27 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Context(t *testing.T) {
+	fallback := &bytes.Buffer{}
+	attached := &bytes.Buffer{}
+
+	logger.FromContext(context.Background(), logger.New("info", fallback)).Info("fallback")
+
+	ctx := logger.WithContext(context.Background(), logger.New("info", attached).With("request_id", "abc"))
+	logger.FromContext(ctx, logger.New("info", fallback)).Info("attached")
+
+	require.Equal(t, "fallback\n", fallback.String())
+	require.Equal(t, "attached request_id=abc\n", attached.String())
+
+	require.NotPanics(t, func() {
+		logger.Nop().Error("discarded")
+	})
+}
@@ -1,8 +1,15 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 type levelType uint8
@@ -15,53 +22,192 @@ const (
 	levelDebug
 )
 
+const badKey = "!BADKEY"
+
+var levelNames = map[levelType]string{
+	levelError:   "error",
+	levelWarning: "warning",
+	levelInfo:    "info",
+	levelDebug:   "debug",
+}
+
+// LogInterface fields are key/value pairs: log.Info("started", "addr", addr).
 type LogInterface interface {
-	Error(string)
-	Warning(string)
-	Info(string)
-	Debug(string)
+	Error(msg string, fields ...any)
+	Warning(msg string, fields ...any)
+	Info(msg string, fields ...any)
+	Debug(msg string, fields ...any)
+	With(fields ...any) LogInterface
 }
 
+type formatter func(buff *bytes.Buffer, level levelType, msg string, fields []any)
+
 type impl struct {
+	mu     *sync.Mutex
 	writer io.Writer
 	level  levelType
+	format formatter
+	fields []any
 }
 
+// New writes the message followed by the fields as key=value.
 func New(level string, writer io.Writer) LogInterface {
-	var uintLevel levelType
+	return &impl{mu: &sync.Mutex{}, level: parseLevel(level), writer: writer, format: formatText}
+}
+
+// NewJSON writes one json object per line with the time, level, message and fields.
+func NewJSON(level string, writer io.Writer) LogInterface {
+	return &impl{mu: &sync.Mutex{}, level: parseLevel(level), writer: writer, format: formatJSON}
+}
+
+// NewFormat selects the output by name: "json" or "text" (default).
+func NewFormat(format string, level string, writer io.Writer) LogInterface {
+	if strings.EqualFold(format, "json") {
+		return NewJSON(level, writer)
+	}
+
+	return New(level, writer)
+}
+
+func parseLevel(level string) levelType {
 	switch strings.ToLower(level) {
 	case "error":
-		uintLevel = levelError
+		return levelError
 	case "warning":
-		uintLevel = levelWarning
+		return levelWarning
 	case "info":
-		uintLevel = levelInfo
+		return levelInfo
 	case "debug":
-		uintLevel = levelDebug
+		return levelDebug
 	default:
-		uintLevel = levelOff
+		return levelOff
 	}
-	return &impl{level: uintLevel, writer: writer}
 }
 
-func (l *impl) Error(msg string) {
-	l.log(levelError, msg)
+func (l *impl) Error(msg string, fields ...any) {
+	l.log(levelError, msg, fields)
 }
 
-func (l *impl) Warning(msg string) {
-	l.log(levelWarning, msg)
+func (l *impl) Warning(msg string, fields ...any) {
+	l.log(levelWarning, msg, fields)
 }
 
-func (l *impl) Info(msg string) {
-	l.log(levelInfo, msg)
+func (l *impl) Info(msg string, fields ...any) {
+	l.log(levelInfo, msg, fields)
 }
 
-func (l *impl) Debug(msg string) {
-	l.log(levelDebug, msg)
+func (l *impl) Debug(msg string, fields ...any) {
+	l.log(levelDebug, msg, fields)
 }
 
-func (l *impl) log(level levelType, msg string) {
-	if l.level >= level {
-		_, _ = l.writer.Write([]byte(msg + "\n"))
+// With returns the logger that adds the fields to every message.
+func (l *impl) With(fields ...any) LogInterface {
+	child := *l
+	child.fields = append(append(make([]any, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+
+	return &child
+}
+
+func (l *impl) log(level levelType, msg string, fields []any) {
+	if l.level < level {
+		return
 	}
+
+	if len(l.fields) > 0 {
+		fields = append(append(make([]any, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+	}
+
+	var buff bytes.Buffer
+	l.format(&buff, level, msg, fields)
+	buff.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, _ = l.writer.Write(buff.Bytes())
+}
+
+// pairs calls fn for every key/value, a value without a key is reported with the "!BADKEY" key.
+func pairs(fields []any, fn func(key string, value any)) {
+	for i := 0; i < len(fields); i++ {
+		key, ok := fields[i].(string)
+		if !ok || i+1 == len(fields) {
+			fn(badKey, fields[i])
+			continue
+		}
+
+		fn(key, fields[i+1])
+		i++
+	}
+}
+
+func formatText(buff *bytes.Buffer, _ levelType, msg string, fields []any) {
+	buff.WriteString(msg)
+	pairs(fields, func(key string, value any) {
+		buff.WriteByte(' ')
+		buff.WriteString(key)
+		buff.WriteByte('=')
+		buff.WriteString(quote(stringify(value)))
+	})
+}
+
+func formatJSON(buff *bytes.Buffer, level levelType, msg string, fields []any) {
+	buff.WriteString(`{"time":`)
+	writeJSON(buff, time.Now().Format(time.RFC3339Nano))
+	buff.WriteString(`,"level":`)
+	writeJSON(buff, levelNames[level])
+	buff.WriteString(`,"msg":`)
+	writeJSON(buff, msg)
+
+	pairs(fields, func(key string, value any) {
+		buff.WriteByte(',')
+		writeJSON(buff, key)
+		buff.WriteByte(':')
+
+		switch val := value.(type) {
+		case error:
+			writeJSON(buff, val.Error())
+		case fmt.Stringer:
+			writeJSON(buff, val.String())
+		default:
+			writeJSON(buff, val)
+		}
+	})
+
+	buff.WriteByte('}')
+}
+
+func writeJSON(buff *bytes.Buffer, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+
+	buff.Write(data)
+}
+
+func stringify(value any) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case error:
+		return val.Error()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// quote values with spaces or quotes, so the line can still be split by spaces.
+func quote(value string) string {
+	if value == "" {
+		return `""`
+	}
+
+	for _, char := range value {
+		if unicode.IsSpace(char) || char == '"' || char == '=' || !unicode.IsPrint(char) {
+			return strconv.Quote(value)
+		}
+	}
+
+	return value
 }
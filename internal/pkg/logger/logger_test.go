@@ -2,6 +2,9 @@ package logger_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/rez1dent3/otus-final/internal/pkg/logger"
@@ -49,3 +52,40 @@ func TestLogger(t *testing.T) {
 		}
 	})
 }
+
+func TestLogger_Fields(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		log := logger.New("debug", buffer)
+
+		log.Info("access", "method", "GET", "status", 200, "agent", "Go client")
+		log.With("request_id", "abc").Error("failed", "error", errors.New("boom"), "odd")
+
+		require.Equal(t, "access method=GET status=200 agent=\"Go client\"\n"+
+			"failed request_id=abc error=boom !BADKEY=odd\n", buffer.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		log := logger.NewFormat("json", "info", buffer).With("request_id", "abc")
+
+		log.Debug("hidden")
+		log.Warning("origin", "status", 502, "error", errors.New("bad gateway"))
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		require.Len(t, lines, 1)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+		require.NotEmpty(t, record["time"])
+		delete(record, "time")
+
+		require.Equal(t, map[string]any{
+			"level":      "warning",
+			"msg":        "origin",
+			"request_id": "abc",
+			"status":     float64(502),
+			"error":      "bad gateway",
+		}, record)
+	})
+}
@@ -0,0 +1,211 @@
+package lru
+
+import (
+	"container/list"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// arc the adaptive replacement cache weighted by the sizes of the entries. "recent" keeps the entries
+// requested once, "frequent" the ones requested again. The ghosts of the evicted entries move the target
+// size of "recent": a hit in "recentGhosts" grows it, a hit in "frequentGhosts" shrinks it.
+type arc struct {
+	base
+
+	recent, frequent             *queue
+	recentGhosts, frequentGhosts *queue
+	items                        map[string]*list.Element
+
+	// target the size of "recent" the eviction aims at.
+	target uint64
+}
+
+func NewARC(name string, sizeLimit uint64, busCommand bus.CommandBusInterface) CacheInterface {
+	return &arc{
+		base:           base{name: name, limit: sizeLimit, busCommand: busCommand},
+		recent:         newQueue(),
+		frequent:       newQueue(),
+		recentGhosts:   newQueue(),
+		frequentGhosts: newQueue(),
+		items:          make(map[string]*list.Element),
+	}
+}
+
+func (c *arc) Put(key string, value any) bool {
+	elementSize := sizeOf(value)
+	if c.limit < elementSize {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+
+	var n *node
+	if ok {
+		n = el.Value.(*node)
+	}
+
+	fromFrequentGhosts := false
+
+	switch {
+	case ok && (n.queue == c.recent || n.queue == c.frequent):
+		c.size = c.size - n.size + elementSize
+		n.queue.remove(el)
+		n.val, n.size = value, elementSize
+		el = c.frequent.push(n)
+	case ok && n.queue == c.recentGhosts:
+		c.target = minSize(c.limit, c.target+ratio(c.frequentGhosts.size, c.recentGhosts.size)*elementSize)
+		c.recentGhosts.remove(el)
+		el = c.frequent.push(&node{key: key, val: value, size: elementSize})
+		c.size += elementSize
+	case ok:
+		c.target -= minSize(c.target, ratio(c.recentGhosts.size, c.frequentGhosts.size)*elementSize)
+		c.frequentGhosts.remove(el)
+		el = c.frequent.push(&node{key: key, val: value, size: elementSize})
+		c.size += elementSize
+		fromFrequentGhosts = true
+	default:
+		el = c.recent.push(&node{key: key, val: value, size: elementSize})
+		c.size += elementSize
+	}
+
+	c.items[key] = el
+
+	c.fire(EventPut, key, elementSize)
+	c.replace(el, fromFrequentGhosts)
+
+	return true
+}
+
+// replace moves the oldest entries to the ghosts until the cache fits, "recent" gives up its entries while
+// it is over the target.
+func (c *arc) replace(keep *list.Element, fromFrequentGhosts bool) {
+	for c.limit < c.size {
+		recent, frequent := c.recent.oldest(keep), c.frequent.oldest(keep)
+
+		switch {
+		case recent != nil && (frequent == nil || c.recent.size > c.target ||
+			(fromFrequentGhosts && c.recent.size == c.target)):
+			c.demote(c.recent, recent, c.recentGhosts)
+		case frequent != nil:
+			c.demote(c.frequent, frequent, c.frequentGhosts)
+		default:
+			return
+		}
+	}
+
+	for c.recent.size+c.recentGhosts.size > c.limit && c.recentGhosts.list.Len() > 0 {
+		delete(c.items, c.recentGhosts.remove(c.recentGhosts.list.Back()).key)
+	}
+
+	for c.size+c.recentGhosts.size+c.frequentGhosts.size > 2*c.limit && c.frequentGhosts.list.Len() > 0 {
+		delete(c.items, c.frequentGhosts.remove(c.frequentGhosts.list.Back()).key)
+	}
+}
+
+func (c *arc) demote(from *queue, el *list.Element, ghosts *queue) {
+	n := from.remove(el)
+	c.items[n.key] = ghosts.push(&node{key: n.key, size: n.size})
+	c.evicted(n.key, n.val, n.size)
+}
+
+// Get a hit makes the entry frequent.
+func (c *arc) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || !c.resident(el) {
+		c.fire(EventMiss, key, 0)
+
+		return nil, false
+	}
+
+	n := el.Value.(*node)
+	n.queue.remove(el)
+	c.items[key] = c.frequent.push(n)
+
+	c.fire(EventHit, key, n.size)
+
+	return n.val, true
+}
+
+func (c *arc) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || !c.resident(el) {
+		c.fire(EventMiss, key, 0)
+
+		return false
+	}
+
+	c.fire(EventHit, key, el.Value.(*node).size)
+
+	return true
+}
+
+func (c *arc) Remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+
+	delete(c.items, key)
+
+	resident := c.resident(el)
+	n := el.Value.(*node)
+	n.queue.remove(el)
+
+	if resident {
+		c.evicted(n.key, n.val, n.size)
+	}
+
+	return resident
+}
+
+func (c *arc) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, q := range []*queue{c.recent, c.frequent} {
+		for q.list.Back() != nil {
+			n := q.remove(q.list.Back())
+			delete(c.items, n.key)
+			c.evicted(n.key, n.val, n.size)
+		}
+	}
+
+	c.recentGhosts, c.frequentGhosts = newQueue(), newQueue()
+	c.items = make(map[string]*list.Element)
+	c.target = 0
+}
+
+func (c *arc) resident(el *list.Element) bool {
+	q := el.Value.(*node).queue
+
+	return q == c.recent || q == c.frequent
+}
+
+// ratio of the ghost sizes, at least 1.
+func ratio(a, b uint64) uint64 {
+	if b == 0 || a <= b {
+		return 1
+	}
+
+	return a / b
+}
+
+func minSize(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -3,16 +3,37 @@ package lru
 import (
 	"container/list"
 	"sync"
+	"time"
 
 	"github.com/rez1dent3/otus-final/internal/pkg/bus"
 )
 
-const EventEvict = "event_evict"
+const (
+	// EventEvict fired with the removed value, subscribers release the resources behind it.
+	EventEvict = "event_evict"
+
+	// EventHit, EventMiss, EventPut and EventRemove are fired with Event and used for statistics.
+	EventHit    = "event_hit"
+	EventMiss   = "event_miss"
+	EventPut    = "event_put"
+	EventRemove = "event_remove"
+)
+
+// Event Size is the size of the element, Total is the size of the whole cache after the operation.
+type Event struct {
+	Cache string
+	Key   string
+	Size  uint64
+	Total uint64
+}
 
 type CacheInterface interface {
 	Put(string, any) bool
 	Get(string) (any, bool)
 	Has(string) bool
+	// Remove reports whether the key was in the cache, the subscribers of EventEvict release the value
+	// as on eviction.
+	Remove(string) bool
 	Size() uint64
 	Purge()
 }
@@ -20,9 +41,13 @@ type CacheInterface interface {
 type entry struct {
 	key string
 	val any
+
+	// expires the zero time means never.
+	expires time.Time
 }
 
 type impl struct {
+	name        string
 	size, limit uint64
 
 	mu sync.RWMutex
@@ -30,15 +55,28 @@ type impl struct {
 	evict *list.List
 	items map[string]*list.Element
 
+	clock clock
+
 	busCommand bus.CommandBusInterface
 }
 
 func New(sizeLimit uint64, busCommand bus.CommandBusInterface) CacheInterface {
-	return &impl{limit: sizeLimit, evict: list.New(), busCommand: busCommand, items: make(map[string]*list.Element)}
+	return NewNamed("", sizeLimit, busCommand)
+}
+
+// NewNamed the name is passed in the events, so subscribers can tell the caches apart.
+func NewNamed(name string, sizeLimit uint64, busCommand bus.CommandBusInterface) CacheInterface {
+	return &impl{
+		name:       name,
+		limit:      sizeLimit,
+		evict:      list.New(),
+		busCommand: busCommand,
+		items:      make(map[string]*list.Element),
+	}
 }
 
 func (c *impl) Put(key string, value any) bool {
-	elementSize := c.elementSize(value)
+	elementSize := sizeOf(value)
 	if c.limit < elementSize {
 		return false
 	}
@@ -50,44 +88,107 @@ func (c *impl) Put(key string, value any) bool {
 	if val, ok := c.items[key]; ok {
 		c.evict.MoveToFront(val)
 		ent := val.Value.(*entry)
-		c.size -= c.elementSize(ent.val) - elementSize
+		c.size -= sizeOf(ent.val) - elementSize
 		ent.val = value
+		ent.expires = c.clock.expires(value)
+		c.fire(EventPut, key, elementSize)
 
 		return true
 	}
 
-	ent := &entry{key: key, val: value}
+	ent := &entry{key: key, val: value, expires: c.clock.expires(value)}
 	item := c.evict.PushFront(ent)
 
 	c.items[key] = item
 	c.size += elementSize
+	c.fire(EventPut, key, elementSize)
 
 	return true
 }
 
+// Get moves the entry to the front of the list, so it needs the write lock. An expired entry is removed.
 func (c *impl) Get(key string) (any, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if ent, ok := c.items[key]; ok {
+	if ent, ok := c.live(key); ok {
 		c.evict.MoveToFront(ent)
+		c.fire(EventHit, key, sizeOf(ent.Value.(*entry).val))
 
 		return ent.Value.(*entry).val, true
 	}
 
+	c.fire(EventMiss, key, 0)
+
 	return nil, false
 }
 
+// Has an expired entry is removed, so it needs the write lock too.
 func (c *impl) Has(key string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	_, ok := c.items[key]
+	ent, ok := c.live(key)
+	if ok {
+		c.fire(EventHit, key, sizeOf(ent.Value.(*entry).val))
+	} else {
+		c.fire(EventMiss, key, 0)
+	}
 
 	return ok
 }
 
+func (c *impl) Remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		c.delete(el)
+	}
+
+	return ok
+}
+
+// live the entry of the key unless it has expired, the expired one is removed.
+func (c *impl) live(key string) (*list.Element, bool) {
+	el, ok := c.items[key]
+	if ok && c.clock.expired(el.Value.(*entry)) {
+		c.delete(el)
+
+		return nil, false
+	}
+
+	return el, ok
+}
+
+// Expire removes the expired entries through EventEvict.
+func (c *impl) Expire() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clock.now == nil {
+		return 0
+	}
+
+	expired := 0
+	for el := c.evict.Back(); el != nil; {
+		prev := el.Prev()
+		if c.clock.expired(el.Value.(*entry)) {
+			c.delete(el)
+			expired++
+		}
+
+		el = prev
+	}
+
+	return expired
+}
+
 func (c *impl) Size() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	return c.size
 }
 
@@ -106,13 +207,19 @@ func (c *impl) delete(el *list.Element) {
 	}
 
 	ent := el.Value.(*entry)
+	elementSize := sizeOf(ent.val)
 
-	c.size -= c.elementSize(ent.val)
+	c.size -= elementSize
 	delete(c.items, ent.key)
 
 	c.evict.Remove(el)
 
 	c.busCommand.Fire(EventEvict, ent.val)
+	c.fire(EventRemove, ent.key, elementSize)
+}
+
+func (c *impl) fire(name string, key string, elementSize uint64) {
+	c.busCommand.Fire(name, Event{Cache: c.name, Key: key, Size: elementSize, Total: c.size})
 }
 
 func (c *impl) removeOldest() {
@@ -121,9 +228,9 @@ func (c *impl) removeOldest() {
 	}
 }
 
-// elementSize If the object implements the "size" method, then we calc the volume by this arg.
+// sizeOf If the object implements the "size" method, then we calc the volume by this arg.
 // Otherwise, we calculate the volume by the number of elements.
-func (c *impl) elementSize(e any) uint64 {
+func sizeOf(e any) uint64 {
 	if val, ok := e.(interface {
 		Size() uint64
 	}); ok {
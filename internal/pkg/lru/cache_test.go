@@ -175,3 +175,65 @@ func TestLru_Evict(t *testing.T) {
 		require.False(t, c.Has("d"))
 	})
 }
+
+func TestLru_Remove(t *testing.T) {
+	commandBus := bus.NewSyncBus()
+
+	var evicted []any
+	commandBus.Subscribe(lru.EventEvict, func(a any) {
+		evicted = append(evicted, a)
+	})
+
+	c := lru.New(5, commandBus)
+	require.True(t, c.Put("a", val{2}))
+	require.True(t, c.Put("b", val{3}))
+
+	require.True(t, c.Remove("a"))
+	require.False(t, c.Remove("a"))
+	require.False(t, c.Has("a"))
+	require.True(t, c.Has("b"))
+	require.Equal(t, uint64(3), c.Size())
+	require.Equal(t, []any{val{2}}, evicted)
+
+	// the freed space is reused without evicting b
+	require.True(t, c.Put("c", val{2}))
+	require.True(t, c.Has("b"))
+	require.Equal(t, []any{val{2}}, evicted)
+}
+
+func TestLru_Events(t *testing.T) {
+	t.Run("stats", func(t *testing.T) {
+		commandBus := bus.NewSyncBus()
+		events := make(map[string][]lru.Event)
+		for _, name := range []string{lru.EventHit, lru.EventMiss, lru.EventPut, lru.EventRemove} {
+			name := name
+			commandBus.Subscribe(name, func(a any) {
+				if e, ok := a.(lru.Event); ok {
+					events[name] = append(events[name], e)
+				}
+			})
+		}
+
+		c := lru.NewNamed("preview", 3, commandBus)
+		require.True(t, c.Put("a", val{2}))
+		require.True(t, c.Put("b", val{1}))
+
+		_, ok := c.Get("a")
+		require.True(t, ok)
+		require.False(t, c.Has("c"))
+
+		require.True(t, c.Put("c", val{2}))
+
+		require.Equal(t, []lru.Event{
+			{Cache: "preview", Key: "a", Size: 2, Total: 2},
+			{Cache: "preview", Key: "b", Size: 1, Total: 3},
+			{Cache: "preview", Key: "c", Size: 2, Total: 5},
+		}, events[lru.EventPut])
+		require.Equal(t, []lru.Event{{Cache: "preview", Key: "a", Size: 2, Total: 3}}, events[lru.EventHit])
+		require.Equal(t, []lru.Event{{Cache: "preview", Key: "c", Size: 0, Total: 3}}, events[lru.EventMiss])
+		require.Equal(t, []lru.Event{
+			{Cache: "preview", Key: "b", Size: 1, Total: 4},
+			{Cache: "preview", Key: "a", Size: 2, Total: 2},
+		}, events[lru.EventRemove])
+	})
+}
@@ -0,0 +1,194 @@
+package lru
+
+import (
+	"container/heap"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// LFUAging the counters are halved after this many requests per entry, so the entries that were popular
+// long ago give way to the new ones.
+const LFUAging = 8
+
+type lfuNode struct {
+	key  string
+	val  any
+	size uint64
+
+	count uint64
+	// tick of the last request, the older entry is evicted first between the equal counts.
+	tick  uint64
+	index int
+}
+
+// lfuHeap the least frequently used entry is on top.
+type lfuHeap []*lfuNode
+
+func (h lfuHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *lfuHeap) Push(x any) {
+	n := x.(*lfuNode)
+	n.index = len(*h)
+	*h = append(*h, n)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return n
+}
+
+type lfu struct {
+	base
+
+	heap  lfuHeap
+	items map[string]*lfuNode
+
+	tick     uint64
+	requests uint64
+}
+
+// NewLFU evicts the least frequently used entries, the counters age with LFUAging.
+func NewLFU(name string, sizeLimit uint64, busCommand bus.CommandBusInterface) CacheInterface {
+	return &lfu{
+		base:  base{name: name, limit: sizeLimit, busCommand: busCommand},
+		items: make(map[string]*lfuNode),
+	}
+}
+
+func (c *lfu) Put(key string, value any) bool {
+	elementSize := sizeOf(value)
+	if c.limit < elementSize {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.items[key]
+	if ok {
+		c.size = c.size - n.size + elementSize
+		n.val, n.size = value, elementSize
+		c.touch(n)
+		heap.Remove(&c.heap, n.index)
+	} else {
+		c.tick++
+		n = &lfuNode{key: key, val: value, size: elementSize, count: 1, tick: c.tick}
+		c.items[key] = n
+		c.size += elementSize
+		c.age()
+	}
+
+	c.fire(EventPut, key, elementSize)
+
+	// the new entry has the lowest count, it is kept out of the heap while the others are evicted
+	for c.limit < c.size && c.heap.Len() > 0 {
+		victim := heap.Pop(&c.heap).(*lfuNode)
+		delete(c.items, victim.key)
+		c.evicted(victim.key, victim.val, victim.size)
+	}
+
+	heap.Push(&c.heap, n)
+
+	return true
+}
+
+func (c *lfu) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.items[key]
+	if !ok {
+		c.fire(EventMiss, key, 0)
+
+		return nil, false
+	}
+
+	c.touch(n)
+	heap.Fix(&c.heap, n.index)
+
+	c.fire(EventHit, key, n.size)
+
+	return n.val, true
+}
+
+// touch counts the request, the caller fixes the position of the node.
+func (c *lfu) touch(n *lfuNode) {
+	c.tick++
+	n.count++
+	n.tick = c.tick
+
+	c.age()
+}
+
+// age halves the counters after LFUAging requests per entry.
+func (c *lfu) age() {
+	c.requests++
+	if c.requests < LFUAging*uint64(len(c.items)) {
+		return
+	}
+
+	c.requests = 0
+	for _, item := range c.heap {
+		item.count = (item.count + 1) / 2
+	}
+
+	heap.Init(&c.heap)
+}
+
+func (c *lfu) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.items[key]
+	if ok {
+		c.fire(EventHit, key, n.size)
+	} else {
+		c.fire(EventMiss, key, 0)
+	}
+
+	return ok
+}
+
+func (c *lfu) Remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.items[key]
+	if ok {
+		heap.Remove(&c.heap, n.index)
+		delete(c.items, key)
+		c.evicted(n.key, n.val, n.size)
+	}
+
+	return ok
+}
+
+func (c *lfu) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.heap.Len() > 0 {
+		n := heap.Pop(&c.heap).(*lfuNode)
+		delete(c.items, n.key)
+		c.evicted(n.key, n.val, n.size)
+	}
+}
@@ -0,0 +1,122 @@
+package lru
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// Names of the eviction policies.
+const (
+	PolicyLRU = "lru"
+	PolicyLFU = "lfu"
+	Policy2Q  = "2q"
+	PolicyARC = "arc"
+)
+
+var ErrPolicy = errors.New("unknown eviction policy")
+
+// NewPolicy the cache with the eviction policy by name, the empty name is PolicyLRU.
+func NewPolicy(
+	policy string,
+	name string,
+	sizeLimit uint64,
+	busCommand bus.CommandBusInterface,
+) (CacheInterface, error) {
+	switch strings.ToLower(policy) {
+	case "", PolicyLRU:
+		return NewNamed(name, sizeLimit, busCommand), nil
+	case PolicyLFU:
+		return NewLFU(name, sizeLimit, busCommand), nil
+	case Policy2Q:
+		return New2Q(name, sizeLimit, busCommand), nil
+	case PolicyARC:
+		return NewARC(name, sizeLimit, busCommand), nil
+	default:
+		return nil, fmt.Errorf("%q: %w", policy, ErrPolicy)
+	}
+}
+
+// base the bookkeeping shared by the policies: the size, the lock and the events.
+type base struct {
+	name        string
+	size, limit uint64
+
+	mu sync.Mutex
+
+	busCommand bus.CommandBusInterface
+}
+
+func (b *base) Size() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+func (b *base) fire(name string, key string, elementSize uint64) {
+	b.busCommand.Fire(name, Event{Cache: b.name, Key: key, Size: elementSize, Total: b.size})
+}
+
+// evicted the entry has already left the cache, its subscribers release the value.
+func (b *base) evicted(key string, val any, elementSize uint64) {
+	b.size -= elementSize
+
+	b.busCommand.Fire(EventEvict, val)
+	b.fire(EventRemove, key, elementSize)
+}
+
+// node an entry of a queue, a ghost keeps only the key and the size of an evicted entry.
+type node struct {
+	key   string
+	val   any
+	size  uint64
+	queue *queue
+}
+
+// queue the front is the most recent entry.
+type queue struct {
+	list *list.List
+	size uint64
+}
+
+func newQueue() *queue {
+	return &queue{list: list.New()}
+}
+
+func (q *queue) push(n *node) *list.Element {
+	n.queue = q
+	q.size += n.size
+
+	return q.list.PushFront(n)
+}
+
+func (q *queue) remove(el *list.Element) *node {
+	n := el.Value.(*node)
+	q.size -= n.size
+	q.list.Remove(el)
+
+	return n
+}
+
+// update replaces the value and moves the entry to the front.
+func (q *queue) update(el *list.Element, val any, size uint64) {
+	n := el.Value.(*node)
+	q.size = q.size - n.size + size
+	n.val, n.size = val, size
+
+	q.list.MoveToFront(el)
+}
+
+// oldest nil if the queue is empty or keeps only the entry that must stay.
+func (q *queue) oldest(keep *list.Element) *list.Element {
+	if el := q.list.Back(); el != keep {
+		return el
+	}
+
+	return nil
+}
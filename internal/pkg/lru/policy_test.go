@@ -0,0 +1,200 @@
+package lru_test
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/stretchr/testify/require"
+)
+
+var policies = []string{lru.PolicyLRU, lru.PolicyLFU, lru.Policy2Q, lru.PolicyARC}
+
+func newPolicy(t testing.TB, policy string, limit uint64, commandBus bus.CommandBusInterface) lru.CacheInterface {
+	t.Helper()
+
+	c, err := lru.NewPolicy(policy, policy, limit, commandBus)
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestNewPolicy(t *testing.T) {
+	c, err := lru.NewPolicy("", "", 1, bus.NewSyncBus())
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	_, err = lru.NewPolicy("mru", "", 1, bus.NewSyncBus())
+	require.ErrorIs(t, err, lru.ErrPolicy)
+}
+
+func TestPolicies_Contract(t *testing.T) {
+	for _, policy := range policies {
+		policy := policy
+
+		t.Run(policy, func(t *testing.T) {
+			commandBus := bus.NewSyncBus()
+
+			resident := make(map[string]bool)
+			commandBus.Subscribe(lru.EventPut, func(a any) {
+				resident[a.(lru.Event).Key] = true
+			})
+			commandBus.Subscribe(lru.EventRemove, func(a any) {
+				delete(resident, a.(lru.Event).Key)
+			})
+
+			evicted := 0
+			commandBus.Subscribe(lru.EventEvict, func(a any) {
+				_, ok := a.(val)
+				require.True(t, ok)
+				evicted++
+			})
+
+			c := newPolicy(t, policy, 20, commandBus)
+			require.False(t, c.Put("huge", val{21}))
+
+			random := rand.New(rand.NewSource(1)) //nolint:gosec
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa(random.Intn(60))
+				if _, ok := c.Get(key); !ok {
+					require.True(t, c.Put(key, val{uint64(1 + random.Intn(4))}))
+					require.True(t, c.Has(key), "the new entry stays")
+				}
+
+				require.LessOrEqual(t, c.Size(), uint64(20))
+			}
+
+			// the events describe the content of the cache
+			total := uint64(0)
+			for key := range resident {
+				res, ok := c.Get(key)
+				require.True(t, ok, key)
+				total += res.(val).size
+			}
+
+			require.Equal(t, total, c.Size())
+			require.Positive(t, evicted)
+
+			for key := range resident {
+				require.True(t, c.Remove(key))
+				require.False(t, c.Remove(key))
+				require.False(t, c.Has(key))
+
+				break
+			}
+
+			c.Purge()
+			require.Empty(t, resident)
+			require.Equal(t, uint64(0), c.Size())
+		})
+	}
+}
+
+func TestPolicies_Replace(t *testing.T) {
+	for _, policy := range policies {
+		c := newPolicy(t, policy, 10, bus.NewSyncBus())
+		require.True(t, c.Put("a", val{3}), policy)
+		require.True(t, c.Put("a", val{5}), policy)
+		require.Equal(t, uint64(5), c.Size(), policy)
+
+		res, ok := c.Get("a")
+		require.True(t, ok, policy)
+		require.Equal(t, val{5}, res, policy)
+	}
+}
+
+// TestPolicies_Scan a sweep through new keys must not flush the popular ones.
+func TestPolicies_Scan(t *testing.T) {
+	for _, policy := range []string{lru.PolicyLFU, lru.Policy2Q, lru.PolicyARC} {
+		c := newPolicy(t, policy, 100, bus.NewSyncBus())
+
+		// the popular keys are requested among the one-off ones
+		for round := 0; round < 5; round++ {
+			for i := 0; i < 20; i++ {
+				key := "popular-" + strconv.Itoa(i)
+				if _, ok := c.Get(key); !ok {
+					c.Put(key, val{1})
+				}
+			}
+
+			for i := 0; i < 50; i++ {
+				c.Put("once-"+strconv.Itoa(round*50+i), val{1})
+			}
+		}
+
+		for i := 0; i < 1000; i++ {
+			c.Put("scan-"+strconv.Itoa(i), val{1})
+		}
+
+		for i := 0; i < 20; i++ {
+			require.True(t, c.Has("popular-"+strconv.Itoa(i)), policy)
+		}
+	}
+}
+
+func TestLFU_Aging(t *testing.T) {
+	c := lru.NewLFU("", 10, bus.NewSyncBus())
+	require.True(t, c.Put("old", val{1}))
+
+	for i := 0; i < 100; i++ {
+		c.Get("old")
+	}
+
+	// the new favourite replaces the old one once the counters have aged
+	for round := 0; round < 200; round++ {
+		for i := 0; i < 3; i++ {
+			if _, ok := c.Get("new"); !ok {
+				c.Put("new", val{1})
+			}
+		}
+
+		c.Put("scan-"+strconv.Itoa(round), val{1})
+	}
+
+	require.True(t, c.Has("new"))
+	require.False(t, c.Has("old"))
+}
+
+// trace the popular keys follow the zipf law, every fifth request is a part of a sweep through the catalogue.
+func trace(n int) []string {
+	random := rand.New(rand.NewSource(1)) //nolint:gosec
+	zipf := rand.NewZipf(random, 1.1, 1, 10000)
+
+	keys := make([]string, n)
+	for i := range keys {
+		if i%5 == 0 {
+			keys[i] = "scan-" + strconv.Itoa(i)
+		} else {
+			keys[i] = strconv.FormatUint(zipf.Uint64(), 10)
+		}
+	}
+
+	return keys
+}
+
+// BenchmarkReplay the hit ratio of the policies on the same trace.
+func BenchmarkReplay(b *testing.B) {
+	keys := trace(100000)
+
+	for _, policy := range policies {
+		b.Run(policy, func(b *testing.B) {
+			hits := 0
+			for i := 0; i < b.N; i++ {
+				c := newPolicy(b, policy, 1000, bus.NewSyncBus())
+
+				hits = 0
+				for _, key := range keys {
+					if _, ok := c.Get(key); ok {
+						hits++
+					} else {
+						c.Put(key, val{1})
+					}
+				}
+			}
+
+			b.ReportMetric(float64(hits)/float64(len(keys)), "hit-ratio")
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// DefaultShards the number of shards of NewSharded if not set.
+const DefaultShards = 16
+
+type shard struct {
+	mu sync.Mutex
+
+	evict *list.List
+	items map[string]*list.Element
+}
+
+// sharded the keys are hashed to the shards with their own locks, the size limit is shared by all of them.
+// The order of eviction is least recently used within a shard: the shard of the new entry gives up its
+// oldest entries first, then the other shards do.
+type sharded struct {
+	name  string
+	limit uint64
+	size  atomic.Uint64
+
+	shards []*shard
+
+	clock clock
+
+	busCommand bus.CommandBusInterface
+}
+
+// NewSharded a cache for the concurrent access, the zero number of shards means DefaultShards.
+func NewSharded(name string, sizeLimit uint64, shards int, busCommand bus.CommandBusInterface) CacheInterface {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+
+	c := &sharded{name: name, limit: sizeLimit, shards: make([]*shard, shards), busCommand: busCommand}
+	for i := range c.shards {
+		c.shards[i] = &shard{evict: list.New(), items: make(map[string]*list.Element)}
+	}
+
+	return c
+}
+
+// shard FNV-1a of the key.
+func (c *sharded) shard(key string) *shard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return c.shards[hash%uint32(len(c.shards))]
+}
+
+func (c *sharded) Put(key string, value any) bool {
+	elementSize := sizeOf(value)
+	if c.limit < elementSize {
+		return false
+	}
+
+	s := c.shard(key)
+	s.mu.Lock()
+
+	el, ok := s.items[key]
+	if ok {
+		s.evict.MoveToFront(el)
+		ent := el.Value.(*entry)
+		c.size.Add(elementSize - sizeOf(ent.val))
+		ent.val = value
+		ent.expires = c.clock.expires(value)
+	} else {
+		el = s.evict.PushFront(&entry{key: key, val: value, expires: c.clock.expires(value)})
+		s.items[key] = el
+		c.size.Add(elementSize)
+	}
+
+	c.fire(EventPut, key, elementSize)
+
+	for c.limit < c.size.Load() && s.evict.Back() != el {
+		c.delete(s, s.evict.Back())
+	}
+
+	s.mu.Unlock()
+
+	// the other shards are locked one at a time, so two puts never wait for each other's shard
+	for _, other := range c.shards {
+		if c.size.Load() <= c.limit {
+			break
+		}
+
+		if other != s {
+			c.shrink(other)
+		}
+	}
+
+	return true
+}
+
+func (c *sharded) shrink(s *shard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c.limit < c.size.Load() && s.evict.Back() != nil {
+		c.delete(s, s.evict.Back())
+	}
+}
+
+// Get moves the entry to the front of the list, so it needs the write lock of the shard.
+func (c *sharded) Get(key string) (any, bool) {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := c.live(s, key); ok {
+		s.evict.MoveToFront(el)
+		val := el.Value.(*entry).val
+		c.fire(EventHit, key, sizeOf(val))
+
+		return val, true
+	}
+
+	c.fire(EventMiss, key, 0)
+
+	return nil, false
+}
+
+func (c *sharded) Has(key string) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := c.live(s, key)
+	if ok {
+		c.fire(EventHit, key, sizeOf(el.Value.(*entry).val))
+	} else {
+		c.fire(EventMiss, key, 0)
+	}
+
+	return ok
+}
+
+func (c *sharded) Remove(key string) bool {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if ok {
+		c.delete(s, el)
+	}
+
+	return ok
+}
+
+// live the entry of the key unless it has expired, the expired one is removed.
+func (c *sharded) live(s *shard, key string) (*list.Element, bool) {
+	el, ok := s.items[key]
+	if ok && c.clock.expired(el.Value.(*entry)) {
+		c.delete(s, el)
+
+		return nil, false
+	}
+
+	return el, ok
+}
+
+// Expire removes the expired entries through EventEvict, a shard at a time.
+func (c *sharded) Expire() int {
+	if c.clock.now == nil {
+		return 0
+	}
+
+	expired := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for el := s.evict.Back(); el != nil; {
+			prev := el.Prev()
+			if c.clock.expired(el.Value.(*entry)) {
+				c.delete(s, el)
+				expired++
+			}
+
+			el = prev
+		}
+		s.mu.Unlock()
+	}
+
+	return expired
+}
+
+func (c *sharded) Size() uint64 {
+	return c.size.Load()
+}
+
+func (c *sharded) Purge() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for s.evict.Back() != nil {
+			c.delete(s, s.evict.Back())
+		}
+		s.mu.Unlock()
+	}
+}
+
+// delete the lock of the shard is held.
+func (c *sharded) delete(s *shard, el *list.Element) {
+	ent := el.Value.(*entry)
+	elementSize := sizeOf(ent.val)
+
+	c.size.Add(^(elementSize - 1))
+	delete(s.items, ent.key)
+
+	s.evict.Remove(el)
+
+	c.busCommand.Fire(EventEvict, ent.val)
+	c.fire(EventRemove, ent.key, elementSize)
+}
+
+func (c *sharded) fire(name string, key string, elementSize uint64) {
+	c.busCommand.Fire(name, Event{Cache: c.name, Key: key, Size: elementSize, Total: c.size.Load()})
+}
@@ -0,0 +1,191 @@
+package lru_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharded_Limits(t *testing.T) {
+	t.Run("limit<size", func(t *testing.T) {
+		c := lru.NewSharded("", 4, 4, bus.NewSyncBus())
+		require.False(t, c.Put("hello", val{5}))
+		require.False(t, c.Has("hello"))
+		require.Equal(t, uint64(0), c.Size())
+	})
+
+	t.Run("entry larger than a shard share", func(t *testing.T) {
+		c := lru.NewSharded("", 8, 4, bus.NewSyncBus())
+		require.True(t, c.Put("hello", val{7}))
+		require.True(t, c.Has("hello"))
+		require.Equal(t, uint64(7), c.Size())
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		c := lru.NewSharded("", 8, 4, bus.NewSyncBus())
+		require.True(t, c.Put("hello", val{5}))
+		require.True(t, c.Put("hello", val{2}))
+		require.Equal(t, uint64(2), c.Size())
+
+		require.True(t, c.Put("hello", val{6}))
+		require.Equal(t, uint64(6), c.Size())
+	})
+
+	t.Run("combined limit", func(t *testing.T) {
+		commandBus := bus.NewSyncBus()
+
+		evicted := 0
+		commandBus.Subscribe(lru.EventEvict, func(any) {
+			evicted++
+		})
+
+		c := lru.NewSharded("", 10, 4, commandBus)
+		for i := 0; i < 100; i++ {
+			require.True(t, c.Put(strconv.Itoa(i), val{1}))
+			require.LessOrEqual(t, c.Size(), uint64(10))
+		}
+
+		require.Equal(t, uint64(10), c.Size())
+		require.Equal(t, 90, evicted)
+
+		// the last one is never evicted by its own put
+		require.True(t, c.Has("99"))
+	})
+}
+
+func TestSharded_Evict(t *testing.T) {
+	t.Run("single shard is lru", func(t *testing.T) {
+		c := lru.NewSharded("", 3, 1, bus.NewSyncBus())
+		require.True(t, c.Put("a", val{1}))
+		require.True(t, c.Put("b", val{1}))
+		require.True(t, c.Put("c", val{1}))
+
+		_, ok := c.Get("a")
+		require.True(t, ok)
+
+		require.True(t, c.Put("d", val{1}))
+		require.True(t, c.Has("a"))
+		require.False(t, c.Has("b"))
+		require.True(t, c.Has("c"))
+		require.True(t, c.Has("d"))
+	})
+
+	t.Run("remove and purge", func(t *testing.T) {
+		commandBus := bus.NewSyncBus()
+
+		var evicted []any
+		commandBus.Subscribe(lru.EventEvict, func(a any) {
+			evicted = append(evicted, a)
+		})
+
+		c := lru.NewSharded("", 10, 4, commandBus)
+		for i := 0; i < 5; i++ {
+			require.True(t, c.Put(strconv.Itoa(i), val{2}))
+		}
+
+		require.True(t, c.Remove("1"))
+		require.False(t, c.Remove("1"))
+		require.False(t, c.Has("1"))
+		require.Equal(t, uint64(8), c.Size())
+		require.Len(t, evicted, 1)
+
+		c.Purge()
+		require.Equal(t, uint64(0), c.Size())
+		require.Len(t, evicted, 5)
+		require.False(t, c.Has("0"))
+	})
+
+	t.Run("events", func(t *testing.T) {
+		commandBus := bus.NewSyncBus()
+
+		var events []lru.Event
+		for _, name := range []string{lru.EventHit, lru.EventMiss, lru.EventPut, lru.EventRemove} {
+			commandBus.Subscribe(name, func(a any) {
+				events = append(events, a.(lru.Event))
+			})
+		}
+
+		c := lru.NewSharded("preview", 3, 1, commandBus)
+		require.True(t, c.Put("a", val{2}))
+		_, _ = c.Get("a")
+		require.False(t, c.Has("b"))
+		require.True(t, c.Put("b", val{2}))
+
+		require.Equal(t, []lru.Event{
+			{Cache: "preview", Key: "a", Size: 2, Total: 2},
+			{Cache: "preview", Key: "a", Size: 2, Total: 2},
+			{Cache: "preview", Key: "b", Size: 0, Total: 2},
+			{Cache: "preview", Key: "b", Size: 2, Total: 4},
+			{Cache: "preview", Key: "a", Size: 2, Total: 2},
+		}, events)
+	})
+}
+
+func TestSharded_Concurrent(t *testing.T) {
+	c := lru.NewSharded("", 100, 8, bus.NewSyncBus())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa((g*1000 + i) % 300)
+				c.Put(key, val{1})
+				c.Get(key)
+				c.Has(key)
+
+				if i%10 == 0 {
+					c.Remove(key)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	require.LessOrEqual(t, c.Size(), uint64(100))
+}
+
+// benchmarkParallel a read-mostly load: 9 of 10 operations are Get, the working set is twice the cache.
+func benchmarkParallel(b *testing.B, c lru.CacheInterface) {
+	b.Helper()
+
+	keys := make([]string, 2048)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[(i*7919)%len(keys)]
+			if i%10 == 0 {
+				c.Put(key, val{1})
+			} else {
+				c.Get(key)
+			}
+
+			i++
+		}
+	})
+}
+
+func BenchmarkParallel(b *testing.B) {
+	b.Run("lru", func(b *testing.B) {
+		benchmarkParallel(b, lru.New(1024, bus.NewSyncBus()))
+	})
+
+	b.Run("sharded", func(b *testing.B) {
+		benchmarkParallel(b, lru.NewSharded("", 1024, lru.DefaultShards, bus.NewSyncBus()))
+	})
+}
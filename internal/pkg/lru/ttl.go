@@ -0,0 +1,110 @@
+package lru
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// Options of NewWithOptions, the zero value is the plain lru of NewNamed.
+type Options struct {
+	// Shards splits the cache into independently locked parts, see NewSharded.
+	Shards int
+
+	// TTL of the entries, 0 keeps them until they are evicted. A value with the TTL() time.Duration method
+	// sets its own.
+	TTL time.Duration
+
+	// Now the clock of the TTL, time.Now by default.
+	Now func() time.Time
+}
+
+// ExpirerInterface is implemented by the caches with the TTL.
+type ExpirerInterface interface {
+	// Expire removes the expired entries through EventEvict and returns their number.
+	Expire() int
+}
+
+// NewWithOptions the lru with the TTL, sharded if the shards are set.
+func NewWithOptions(name string, sizeLimit uint64, busCommand bus.CommandBusInterface, options Options) CacheInterface {
+	clock := newClock(options)
+
+	if options.Shards > 0 {
+		c := NewSharded(name, sizeLimit, options.Shards, busCommand).(*sharded)
+		c.clock = clock
+
+		return c
+	}
+
+	c := NewNamed(name, sizeLimit, busCommand).(*impl)
+	c.clock = clock
+
+	return c
+}
+
+// clock the expiry of the entries, the zero ttl never expires.
+type clock struct {
+	ttl time.Duration
+	now func() time.Time
+}
+
+func newClock(options Options) clock {
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return clock{ttl: options.TTL, now: options.Now}
+}
+
+// expires the zero time means never.
+func (c clock) expires(value any) time.Time {
+	ttl := c.ttl
+	if val, ok := value.(interface {
+		TTL() time.Duration
+	}); ok && val.TTL() > 0 {
+		ttl = val.TTL()
+	}
+
+	if ttl <= 0 || c.now == nil {
+		return time.Time{}
+	}
+
+	return c.now().Add(ttl)
+}
+
+func (c clock) expired(ent *entry) bool {
+	return !ent.expires.IsZero() && !c.now().Before(ent.expires)
+}
+
+// StartJanitor expires the entries of the cache every interval, until stop is called. Stop waits for the
+// janitor to finish and can be called more than once.
+func StartJanitor(cache ExpirerInterface, interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				cache.Expire()
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
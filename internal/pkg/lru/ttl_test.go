@@ -0,0 +1,178 @@
+package lru_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+type ttlVal struct {
+	size uint64
+	ttl  time.Duration
+}
+
+func (v ttlVal) Size() uint64 {
+	return v.size
+}
+
+func (v ttlVal) TTL() time.Duration {
+	return v.ttl
+}
+
+// evictions counts the values released through EventEvict.
+func evictions(commandBus bus.CommandBusInterface) *[]any {
+	var evicted []any
+	commandBus.Subscribe(lru.EventEvict, func(a any) {
+		evicted = append(evicted, a)
+	})
+
+	return &evicted
+}
+
+func TestTTL(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		shards := shards
+
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2022, time.November, 5, 10, 0, 0, 0, time.UTC)}
+			options := lru.Options{Shards: shards, TTL: time.Minute, Now: clock.Now}
+
+			t.Run("lazy expiry", func(t *testing.T) {
+				commandBus := bus.NewSyncBus()
+				evicted := evictions(commandBus)
+
+				c := lru.NewWithOptions("", 10, commandBus, options)
+				require.True(t, c.Put("a", val{1}))
+				require.True(t, c.Put("b", val{2}))
+
+				clock.Advance(59 * time.Second)
+				_, ok := c.Get("a")
+				require.True(t, ok)
+
+				clock.Advance(time.Second)
+				_, ok = c.Get("a")
+				require.False(t, ok)
+				require.False(t, c.Has("b"))
+
+				require.Equal(t, []any{val{1}, val{2}}, *evicted)
+				require.Equal(t, uint64(0), c.Size())
+			})
+
+			t.Run("put renews", func(t *testing.T) {
+				c := lru.NewWithOptions("", 10, bus.NewSyncBus(), options)
+				require.True(t, c.Put("a", val{1}))
+
+				clock.Advance(30 * time.Second)
+				require.True(t, c.Put("a", val{1}))
+
+				clock.Advance(45 * time.Second)
+				require.True(t, c.Has("a"))
+			})
+
+			t.Run("entry ttl", func(t *testing.T) {
+				c := lru.NewWithOptions("", 10, bus.NewSyncBus(), options)
+				require.True(t, c.Put("short", ttlVal{1, time.Second}))
+				require.True(t, c.Put("long", ttlVal{1, time.Hour}))
+				require.True(t, c.Put("default", ttlVal{1, 0}))
+
+				clock.Advance(time.Second)
+				require.False(t, c.Has("short"))
+				require.True(t, c.Has("default"))
+
+				clock.Advance(time.Minute)
+				require.False(t, c.Has("default"))
+				require.True(t, c.Has("long"))
+			})
+
+			t.Run("expire", func(t *testing.T) {
+				commandBus := bus.NewSyncBus()
+				evicted := evictions(commandBus)
+
+				c := lru.NewWithOptions("", 10, commandBus, options).(lru.ExpirerInterface)
+				cache := c.(lru.CacheInterface)
+				require.True(t, cache.Put("a", val{1}))
+				require.True(t, cache.Put("b", ttlVal{2, time.Hour}))
+
+				require.Equal(t, 0, c.Expire())
+
+				clock.Advance(time.Minute)
+				require.Equal(t, 1, c.Expire())
+				require.Equal(t, []any{val{1}}, *evicted)
+				require.Equal(t, uint64(2), cache.Size())
+			})
+		})
+	}
+
+	t.Run("no ttl", func(t *testing.T) {
+		c := lru.NewWithOptions("", 10, bus.NewSyncBus(), lru.Options{})
+		require.True(t, c.Put("a", val{1}))
+		require.Equal(t, 0, c.(lru.ExpirerInterface).Expire())
+		require.True(t, c.Has("a"))
+	})
+}
+
+func TestJanitor(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+
+	var mu sync.Mutex
+	evicted := 0
+
+	commandBus := bus.NewSyncBus()
+	commandBus.Subscribe(lru.EventEvict, func(any) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		evicted++
+	})
+
+	c := lru.NewWithOptions("", 10, commandBus, lru.Options{TTL: time.Minute, Now: clock.Now})
+	require.True(t, c.Put("a", val{1}))
+
+	stop := lru.StartJanitor(c.(lru.ExpirerInterface), time.Millisecond)
+
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return evicted == 1
+	}, time.Second, time.Millisecond)
+
+	stop()
+	stop()
+
+	// the stopped janitor leaves the expired entries to the lazy expiry
+	require.True(t, c.Put("b", val{1}))
+	clock.Advance(time.Minute)
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	require.Equal(t, 1, evicted)
+	mu.Unlock()
+
+	require.False(t, c.Has("b"))
+}
@@ -0,0 +1,169 @@
+package lru
+
+import (
+	"container/list"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+)
+
+// twoQueue the 2Q policy: a new entry waits in the fifo "in" queue, an entry requested again after it has
+// left "in" goes to the lru "main" queue. A single sweep through the keys never reaches "main", so it can't
+// flush the popular entries. "out" remembers the keys evicted from "in" without their values.
+type twoQueue struct {
+	base
+
+	in, main, out *queue
+	items         map[string]*list.Element
+
+	// inLimit and outLimit the sizes of "in" and of the keys in "out".
+	inLimit, outLimit uint64
+}
+
+// New2Q "in" takes a quarter of the limit, "out" remembers the keys of a half of it.
+func New2Q(name string, sizeLimit uint64, busCommand bus.CommandBusInterface) CacheInterface {
+	return &twoQueue{
+		base:     base{name: name, limit: sizeLimit, busCommand: busCommand},
+		in:       newQueue(),
+		main:     newQueue(),
+		out:      newQueue(),
+		items:    make(map[string]*list.Element),
+		inLimit:  sizeLimit / 4,
+		outLimit: sizeLimit / 2,
+	}
+}
+
+func (c *twoQueue) Put(key string, value any) bool {
+	elementSize := sizeOf(value)
+	if c.limit < elementSize {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+
+	switch {
+	case ok && el.Value.(*node).queue != c.out:
+		n := el.Value.(*node)
+		c.size = c.size - n.size + elementSize
+		n.queue.update(el, value, elementSize)
+	case ok:
+		// requested again after it has left "in"
+		c.out.remove(el)
+		el = c.main.push(&node{key: key, val: value, size: elementSize})
+		c.items[key] = el
+		c.size += elementSize
+	default:
+		el = c.in.push(&node{key: key, val: value, size: elementSize})
+		c.items[key] = el
+		c.size += elementSize
+	}
+
+	c.fire(EventPut, key, elementSize)
+	c.reclaim(el)
+
+	return true
+}
+
+// reclaim "in" gives up its oldest entries while it is over its share, then "main" does.
+func (c *twoQueue) reclaim(keep *list.Element) {
+	for c.limit < c.size {
+		switch {
+		case c.in.size > c.inLimit && c.in.oldest(keep) != nil:
+			n := c.in.remove(c.in.oldest(keep))
+			c.items[n.key] = c.out.push(&node{key: n.key, size: n.size})
+			c.evicted(n.key, n.val, n.size)
+		case c.main.oldest(keep) != nil:
+			n := c.main.remove(c.main.oldest(keep))
+			delete(c.items, n.key)
+			c.evicted(n.key, n.val, n.size)
+		case c.in.oldest(keep) != nil:
+			n := c.in.remove(c.in.oldest(keep))
+			c.items[n.key] = c.out.push(&node{key: n.key, size: n.size})
+			c.evicted(n.key, n.val, n.size)
+		default:
+			return
+		}
+	}
+
+	for c.out.size > c.outLimit {
+		delete(c.items, c.out.remove(c.out.list.Back()).key)
+	}
+}
+
+// Get an entry of "main" becomes the most recent, the order of "in" is kept.
+func (c *twoQueue) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || el.Value.(*node).queue == c.out {
+		c.fire(EventMiss, key, 0)
+
+		return nil, false
+	}
+
+	n := el.Value.(*node)
+	if n.queue == c.main {
+		c.main.list.MoveToFront(el)
+	}
+
+	c.fire(EventHit, key, n.size)
+
+	return n.val, true
+}
+
+func (c *twoQueue) Has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok || el.Value.(*node).queue == c.out {
+		c.fire(EventMiss, key, 0)
+
+		return false
+	}
+
+	c.fire(EventHit, key, el.Value.(*node).size)
+
+	return true
+}
+
+func (c *twoQueue) Remove(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+
+	delete(c.items, key)
+
+	n := el.Value.(*node)
+	n.queue.remove(el)
+	if n.queue == c.out {
+		return false
+	}
+
+	c.evicted(n.key, n.val, n.size)
+
+	return true
+}
+
+func (c *twoQueue) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, q := range []*queue{c.in, c.main} {
+		for q.list.Back() != nil {
+			n := q.remove(q.list.Back())
+			delete(c.items, n.key)
+			c.evicted(n.key, n.val, n.size)
+		}
+	}
+
+	c.out = newQueue()
+	c.items = make(map[string]*list.Element)
+}
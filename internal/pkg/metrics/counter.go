@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type counter struct {
+	*vec
+}
+
+func (c *counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add the counter can only go up, so negative values are ignored.
+func (c *counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.with(labelValues).value += value
+}
+
+func (c *counter) write(w io.Writer) error {
+	return writeValues(w, c.vec)
+}
+
+func writeValues(w io.Writer, v *vec) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.series) == 0 {
+		return nil
+	}
+
+	if err := v.header(w); err != nil {
+		return err
+	}
+
+	for _, s := range v.sorted() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, v.labelPairs(s), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import "io"
+
+type gauge struct {
+	*vec
+}
+
+func (g *gauge) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.with(labelValues).value = value
+}
+
+func (g *gauge) Add(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.with(labelValues).value += value
+}
+
+func (g *gauge) write(w io.Writer) error {
+	return writeValues(w, g.vec)
+}
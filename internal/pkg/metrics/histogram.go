@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+)
+
+type histogram struct {
+	*vec
+	buckets []float64
+}
+
+func (h *histogram) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.with(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+
+	s.sum += value
+	s.count++
+}
+
+func (h *histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.series) == 0 {
+		return nil
+	}
+
+	if err := h.header(w); err != nil {
+		return err
+	}
+
+	for _, s := range h.sorted() {
+		for i, bound := range h.buckets {
+			_, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s, "le", formatFloat(bound)), s.counts[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, h.labelPairs(s, "le", "+Inf"), s.count,
+			h.name, h.labelPairs(s), formatFloat(s.sum),
+			h.name, h.labelPairs(s), s.count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
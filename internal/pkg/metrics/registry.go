@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefBuckets latency buckets in seconds, the same as the prometheus client uses.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type CounterInterface interface {
+	Inc(labelValues ...string)
+	Add(value float64, labelValues ...string)
+}
+
+type GaugeInterface interface {
+	Set(value float64, labelValues ...string)
+	Add(value float64, labelValues ...string)
+}
+
+type HistogramInterface interface {
+	Observe(value float64, labelValues ...string)
+}
+
+type RegistryInterface interface {
+	Counter(name, help string, labels ...string) CounterInterface
+	Gauge(name, help string, labels ...string) GaugeInterface
+	Histogram(name, help string, buckets []float64, labels ...string) HistogramInterface
+	WriteTo(io.Writer) (int64, error)
+}
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+type impl struct {
+	mu sync.Mutex
+
+	names      []string
+	collectors map[string]collector
+}
+
+func New() RegistryInterface {
+	return &impl{collectors: make(map[string]collector)}
+}
+
+func (r *impl) Counter(name, help string, labels ...string) CounterInterface {
+	return r.register(name, func() collector {
+		return &counter{vec: newVec(name, help, typeCounter, labels)}
+	}).(CounterInterface)
+}
+
+func (r *impl) Gauge(name, help string, labels ...string) GaugeInterface {
+	return r.register(name, func() collector {
+		return &gauge{vec: newVec(name, help, typeGauge, labels)}
+	}).(GaugeInterface)
+}
+
+func (r *impl) Histogram(name, help string, buckets []float64, labels ...string) HistogramInterface {
+	return r.register(name, func() collector {
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+
+		return &histogram{vec: newVec(name, help, typeHistogram, labels), buckets: sorted}
+	}).(HistogramInterface)
+}
+
+// register returns the already registered collector, so the same metric can be requested several times.
+func (r *impl) register(name string, create func() collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.collectors[name]; ok {
+		return c
+	}
+
+	c := create()
+	r.collectors[name] = c
+	r.names = append(r.names, name)
+	sort.Strings(r.names)
+
+	return c
+}
+
+// WriteTo writes all metrics in the prometheus text exposition format.
+func (r *impl) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	list := make([]collector, 0, len(r.names))
+	for _, name := range r.names {
+		list = append(list, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, c := range list {
+		if err := c.write(cw); err != nil {
+			return cw.n, err
+		}
+	}
+
+	return cw.n, cw.w.Flush()
+}
+
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+type vec struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*series)}
+}
+
+// with returns the series for the values, missing values are treated as empty and extra ones are dropped.
+// Must be called under the lock.
+func (v *vec) with(labelValues []string) *series {
+	values := make([]string, len(v.labels))
+	copy(values, labelValues)
+
+	key := strings.Join(values, "\xff")
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+
+	s := &series{labelValues: values}
+	v.series[key] = s
+
+	return s
+}
+
+func (v *vec) sorted() []*series {
+	list := make([]*series, 0, len(v.series))
+	for _, s := range v.series {
+		list = append(list, s)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].labelValues, "\xff") < strings.Join(list[j].labelValues, "\xff")
+	})
+
+	return list
+}
+
+func (v *vec) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+
+	return err
+}
+
+func (v *vec) labelPairs(s *series, extra ...string) string {
+	pairs := make([]string, 0, len(v.labels)+1)
+	for i, label := range v.labels {
+		pairs = append(pairs, label+`="`+escapeLabel(s.labelValues[i])+`"`)
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabel the text format escapes only the backslash, the double quote and the line feed.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
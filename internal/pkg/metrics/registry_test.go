@@ -0,0 +1,92 @@
+package metrics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func exposition(t *testing.T, registry metrics.RegistryInterface) string {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	n, err := registry.WriteTo(buffer)
+	require.NoError(t, err)
+	require.Equal(t, int64(buffer.Len()), n)
+
+	return buffer.String()
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		registry := metrics.New()
+		registry.Counter("requests_total", "Total requests.", "code")
+
+		require.Equal(t, "", exposition(t, registry))
+	})
+
+	t.Run("counter", func(t *testing.T) {
+		registry := metrics.New()
+		c := registry.Counter("requests_total", "Total requests.", "route", "code")
+		c.Inc("/fill/", "200")
+		c.Inc("/fill/", "200")
+		c.Add(3, "/health", "200")
+		c.Add(-1, "/health", "200")
+
+		require.Equal(t, `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{route="/fill/",code="200"} 2
+requests_total{route="/health",code="200"} 3
+`, exposition(t, registry))
+	})
+
+	t.Run("gauge", func(t *testing.T) {
+		registry := metrics.New()
+		g := registry.Gauge("cache_bytes", "Cache size.", "cache")
+		g.Set(10, "preview")
+		g.Add(-4, "preview")
+		g.Set(1.5, `or"ig`)
+		g.Set(2, "пре\\вью\n")
+
+		require.Equal(t, `# HELP cache_bytes Cache size.
+# TYPE cache_bytes gauge
+cache_bytes{cache="or\"ig"} 1.5
+cache_bytes{cache="preview"} 6
+cache_bytes{cache="пре\\вью\n"} 2
+`, exposition(t, registry))
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		registry := metrics.New()
+		h := registry.Histogram("duration_seconds", "Duration.", []float64{1, 0.1}, "format")
+		h.Observe(0.05, "jpeg")
+		h.Observe(0.5, "jpeg")
+		h.Observe(2, "jpeg")
+
+		require.Equal(t, `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{format="jpeg",le="0.1"} 1
+duration_seconds_bucket{format="jpeg",le="1"} 2
+duration_seconds_bucket{format="jpeg",le="+Inf"} 3
+duration_seconds_sum{format="jpeg"} 2.55
+duration_seconds_count{format="jpeg"} 3
+`, exposition(t, registry))
+	})
+
+	t.Run("same name", func(t *testing.T) {
+		registry := metrics.New()
+		registry.Counter("hits_total", "Hits.").Inc()
+		registry.Counter("hits_total", "Hits.").Inc()
+		registry.Gauge("a_bytes", "Bytes.").Set(1)
+
+		require.Equal(t, `# HELP a_bytes Bytes.
+# TYPE a_bytes gauge
+a_bytes 1
+# HELP hits_total Hits.
+# TYPE hits_total counter
+hits_total 2
+`, exposition(t, registry))
+	})
+}
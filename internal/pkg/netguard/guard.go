@@ -0,0 +1,85 @@
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+var ErrBlockedAddress = errors.New("destination address is blocked")
+
+// DefaultBlocked private, loopback, link-local and other special purpose networks.
+var DefaultBlocked = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// GuardInterface checks the resolved address right before connecting,
+// so neither dns rebinding nor redirects can reach the blocked networks.
+type GuardInterface interface {
+	Check(ip net.IP) error
+	Control(network, address string, c syscall.RawConn) error
+}
+
+type impl struct {
+	blocked, allowed hostmatch.MatcherInterface
+}
+
+// New the allowed networks override the blocked ones, nil blocked means DefaultBlocked.
+func New(blocked, allowed []string) (GuardInterface, error) {
+	if blocked == nil {
+		blocked = DefaultBlocked
+	}
+
+	blockedMatcher, err := hostmatch.New(blocked)
+	if err != nil {
+		return nil, fmt.Errorf("blocked networks: %w", err)
+	}
+
+	allowedMatcher, err := hostmatch.New(allowed)
+	if err != nil {
+		return nil, fmt.Errorf("allowed networks: %w", err)
+	}
+
+	return &impl{blocked: blockedMatcher, allowed: allowedMatcher}, nil
+}
+
+func (g *impl) Check(ip net.IP) error {
+	if ip == nil {
+		return ErrBlockedAddress
+	}
+
+	if g.blocked.MatchIP(ip) && !g.allowed.MatchIP(ip) {
+		return fmt.Errorf("%s: %w", ip, ErrBlockedAddress)
+	}
+
+	return nil
+}
+
+// Control fits net.Dialer.Control, the address is always an ip there.
+func (g *impl) Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	return g.Check(net.ParseIP(host))
+}
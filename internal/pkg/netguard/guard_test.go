@@ -0,0 +1,91 @@
+package netguard_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard_Check(t *testing.T) {
+	guard, err := netguard.New(nil, []string{"172.18.0.0/16"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"8.8.8.8", false},
+		{"140.82.121.4", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.0.10", true},
+		{"172.16.0.1", true},
+		{"172.18.0.5", false},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd12::1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+		{"2a00:1450:4001::1", false},
+	}
+
+	for _, testCase := range testCases {
+		err := guard.Check(net.ParseIP(testCase.ip))
+		if testCase.blocked {
+			require.ErrorIs(t, err, netguard.ErrBlockedAddress, testCase.ip)
+		} else {
+			require.NoError(t, err, testCase.ip)
+		}
+	}
+
+	require.ErrorIs(t, guard.Check(nil), netguard.ErrBlockedAddress)
+}
+
+func TestGuard_Custom(t *testing.T) {
+	guard, err := netguard.New([]string{"8.8.8.0/24"}, nil)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, guard.Check(net.ParseIP("8.8.8.8")), netguard.ErrBlockedAddress)
+	require.NoError(t, guard.Check(net.ParseIP("127.0.0.1")))
+
+	_, err = netguard.New([]string{"8.8.8.0/33"}, nil)
+	require.ErrorIs(t, err, hostmatch.ErrPattern)
+}
+
+func TestGuard_Control(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	guard, err := netguard.New(nil, nil)
+	require.NoError(t, err)
+
+	dialer := &net.Dialer{Control: guard.Control}
+	client := http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req) //nolint:bodyclose
+	require.ErrorIs(t, err, netguard.ErrBlockedAddress)
+	require.Nil(t, resp)
+
+	// localhost is resolved first, so the name does not help
+	req, err = http.NewRequestWithContext(context.Background(), http.MethodGet,
+		"http://localhost:"+strconv.Itoa(server.Listener.Addr().(*net.TCPAddr).Port), nil)
+	require.NoError(t, err)
+
+	resp, err = client.Do(req) //nolint:bodyclose
+	require.ErrorIs(t, err, netguard.ErrBlockedAddress)
+	require.Nil(t, resp)
+}
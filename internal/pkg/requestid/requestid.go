@@ -0,0 +1,54 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	Header    = "X-Request-ID"
+	maxLength = 128
+)
+
+type ctxKey struct{}
+
+// Generate 128 random bits in hex.
+func Generate() string {
+	buff := make([]byte, 16)
+	if _, err := rand.Read(buff); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(buff)
+}
+
+// Valid accepts only short ids of letters, digits and -_.:, so an incoming id is safe to log and forward.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, char := range id {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case char == '-', char == '_', char == '.', char == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(ctxKey{}).(string); ok {
+		return id
+	}
+
+	return ""
+}
@@ -0,0 +1,44 @@
+package requestid_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/requestid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	first, second := requestid.Generate(), requestid.Generate()
+
+	require.Len(t, first, 32)
+	require.NotEqual(t, first, second)
+	require.True(t, requestid.Valid(first))
+}
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id    string
+		valid bool
+	}{
+		{"", false},
+		{"abc-123_DEF.4:5", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"with space", false},
+		{"line\nbreak", false},
+		{"кириллица", false},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.valid, requestid.Valid(testCase.id), testCase.id)
+	}
+}
+
+func TestContext(t *testing.T) {
+	require.Equal(t, "", requestid.FromContext(context.Background()))
+
+	ctx := requestid.WithContext(context.Background(), "abc")
+	require.Equal(t, "abc", requestid.FromContext(ctx))
+}
@@ -0,0 +1,156 @@
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm = "AWS4-HMAC-SHA256"
+
+	// EmptyPayload the hash of an empty body.
+	EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	timeFormat = "20060102T150405Z"
+	dateFormat = "20060102"
+)
+
+type Credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// Sign adds the Authorization header of AWS Signature Version 4 to the request without a body.
+// Every header set on the request is signed along with the host.
+func Sign(req *http.Request, credentials Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+
+	req.Header.Set("X-Amz-Date", now.Format(timeFormat))
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", EmptyPayload)
+	}
+
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	signedHeaders, canonicalHeaders := headers(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.EscapedPath()),
+		query(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(dateFormat), region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{algorithm, now.Format(timeFormat), scope, hash(canonicalRequest)}, "\n")
+
+	key := mac([]byte("AWS4"+credentials.SecretKey), now.Format(dateFormat))
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = mac(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, credentials.AccessKey, scope, signedHeaders, hex.EncodeToString(mac(key, stringToSign))))
+}
+
+// headers the sorted lower case names and the "name:value\n" lines, the Authorization itself is not signed.
+func headers(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	values := map[string]string{"host": host}
+	for name, value := range req.Header {
+		name = strings.ToLower(name)
+		if name != "authorization" {
+			values[name] = strings.Join(strings.Fields(strings.Join(value, ",")), " ")
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + values[name] + "\n")
+	}
+
+	return strings.Join(names, ";"), canonical.String()
+}
+
+func query(values url.Values) string {
+	pairs := make([]string, 0, len(values))
+	for name, list := range values {
+		for _, value := range list {
+			pairs = append(pairs, escape(name)+"="+escape(value))
+		}
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// escapePath re-encodes the path by the rules of AWS: every byte except the unreserved ones and "/".
+func escapePath(escaped string) string {
+	if escaped == "" {
+		return "/"
+	}
+
+	unescaped, err := url.PathUnescape(escaped)
+	if err != nil {
+		return escaped
+	}
+
+	segments := strings.Split(unescaped, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// escape by RFC 3986, the unreserved characters are kept.
+func escape(value string) string {
+	var result strings.Builder
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' {
+			result.WriteByte(b)
+			continue
+		}
+
+		fmt.Fprintf(&result, "%%%02X", b)
+	}
+
+	return result.String()
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func mac(key []byte, value string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(value))
+
+	return h.Sum(nil)
+}
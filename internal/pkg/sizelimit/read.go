@@ -0,0 +1,32 @@
+package sizelimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrTooLarge = errors.New("content is too large")
+
+// ReadAll checks the declared length first and then reads no more than limit+1 bytes,
+// so neither a missing nor a false Content-Length lets a huge body in. Zero limit reads everything.
+func ReadAll(body io.Reader, contentLength int64, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+
+	if contentLength > limit {
+		return nil, fmt.Errorf("declared %d bytes, limit %d: %w", contentLength, limit, ErrTooLarge)
+	}
+
+	buff, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(buff)) > limit {
+		return nil, fmt.Errorf("more than %d bytes: %w", limit, ErrTooLarge)
+	}
+
+	return buff, nil
+}
@@ -0,0 +1,64 @@
+package sizelimit_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/stretchr/testify/require"
+)
+
+type countReader struct {
+	reader io.Reader
+	read   int
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += n
+
+	return n, err
+}
+
+func TestReadAll(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          string
+		contentLength int64
+		limit         int64
+		tooLarge      bool
+	}{
+		{"no limit", "hello world", -1, 0, false},
+		{"unknown length", "hello", -1, 5, false},
+		{"unknown length, too large", "hello world", -1, 5, true},
+		{"declared", "hello", 5, 5, false},
+		{"declared too large", "hello world", 11, 5, true},
+		{"declared less than sent", "hello world", 3, 5, true},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			body, err := sizelimit.ReadAll(strings.NewReader(testCase.body), testCase.contentLength, testCase.limit)
+			if testCase.tooLarge {
+				require.ErrorIs(t, err, sizelimit.ErrTooLarge)
+				require.Nil(t, body)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.body, string(body))
+		})
+	}
+
+	t.Run("stops reading", func(t *testing.T) {
+		reader := &countReader{reader: bytes.NewReader(make([]byte, 1<<20))}
+
+		_, err := sizelimit.ReadAll(reader, -1, 1024)
+		require.ErrorIs(t, err, sizelimit.ErrTooLarge)
+		require.LessOrEqual(t, reader.read, 1025)
+	})
+}
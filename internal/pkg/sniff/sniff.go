@@ -0,0 +1,94 @@
+package sniff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const Unknown = "application/octet-stream"
+
+var (
+	ErrNotSupported = errors.New("content type is not supported")
+	ErrMismatch     = errors.New("declared content type does not match the content")
+)
+
+type signature struct {
+	offset int
+	magic  []byte
+}
+
+// signatures the magic bytes of the image formats, the first match wins.
+var signatures = []struct {
+	contentType string
+	parts       []signature
+}{
+	{"image/jpeg", []signature{{0, []byte{0xFF, 0xD8, 0xFF}}}},
+	{"image/png", []signature{{0, []byte("\x89PNG\r\n\x1a\n")}}},
+	{"image/gif", []signature{{0, []byte("GIF87a")}}},
+	{"image/gif", []signature{{0, []byte("GIF89a")}}},
+	{"image/webp", []signature{{0, []byte("RIFF")}, {8, []byte("WEBP")}}},
+	{"image/bmp", []signature{{0, []byte("BM")}}},
+	{"image/tiff", []signature{{0, []byte("II*\x00")}}},
+	{"image/tiff", []signature{{0, []byte("MM\x00*")}}},
+	{"image/avif", []signature{{4, []byte("ftypavif")}}},
+}
+
+// Detect the content type by the magic bytes, Unknown if the format is not an image.
+func Detect(body []byte) string {
+	for _, s := range signatures {
+		matched := true
+		for _, part := range s.parts {
+			end := part.offset + len(part.magic)
+			if len(body) < end || !bytes.Equal(body[part.offset:end], part.magic) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return s.contentType
+		}
+	}
+
+	return Unknown
+}
+
+// CheckerInterface the single validation of the originals, whatever the source.
+type CheckerInterface interface {
+	// Check returns the detected content type of the body, declared is the type reported by the source.
+	Check(declared string, body []byte) (string, error)
+}
+
+type impl struct {
+	supported map[string]struct{}
+	strict    bool
+}
+
+// New in the strict mode the declared type must match the content, the lenient mode trusts only the content.
+func New(supported []string, strict bool) CheckerInterface {
+	c := &impl{supported: make(map[string]struct{}, len(supported)), strict: strict}
+	for _, contentType := range supported {
+		c.supported[strings.ToLower(contentType)] = struct{}{}
+	}
+
+	return c
+}
+
+func (c *impl) Check(declared string, body []byte) (string, error) {
+	detected := Detect(body)
+	if _, ok := c.supported[detected]; !ok {
+		return detected, fmt.Errorf("%s: %w", detected, ErrNotSupported)
+	}
+
+	if c.strict {
+		mediaType, _, err := mime.ParseMediaType(declared)
+		if err != nil || !strings.EqualFold(mediaType, detected) {
+			return detected, fmt.Errorf("declared %q, detected %s: %w", declared, detected, ErrMismatch)
+		}
+	}
+
+	return detected, nil
+}
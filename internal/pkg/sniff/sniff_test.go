@@ -0,0 +1,76 @@
+package sniff_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/stretchr/testify/require"
+)
+
+func image(t *testing.T, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile("../../../resources/images/" + name)
+	require.NoError(t, err)
+
+	return body
+}
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		body     []byte
+		expected string
+	}{
+		{image(t, "_gopher_original_1024x504.jpg"), "image/jpeg"},
+		{image(t, "_gopher_original_1024x504.png"), "image/png"},
+		{image(t, "_gopher_original_1024x504.webp"), "image/webp"},
+		{[]byte("GIF89a..."), "image/gif"},
+		{[]byte("RIFF\x00\x00\x00\x00WAVE"), sniff.Unknown},
+		{[]byte("<html></html>"), sniff.Unknown},
+		{[]byte{0xFF, 0xD8}, sniff.Unknown},
+		{nil, sniff.Unknown},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.expected, sniff.Detect(testCase.body))
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	jpeg := image(t, "_gopher_original_1024x504.jpg")
+	webp := image(t, "_gopher_original_1024x504.webp")
+
+	t.Run("lenient", func(t *testing.T) {
+		checker := sniff.New([]string{"image/jpeg", "image/png"}, false)
+
+		for _, declared := range []string{"image/jpeg", "application/octet-stream", "", "text/html"} {
+			detected, err := checker.Check(declared, jpeg)
+			require.NoError(t, err, declared)
+			require.Equal(t, "image/jpeg", detected)
+		}
+
+		_, err := checker.Check("image/jpeg", webp)
+		require.ErrorIs(t, err, sniff.ErrNotSupported)
+
+		_, err = checker.Check("image/jpeg", []byte("<html></html>"))
+		require.ErrorIs(t, err, sniff.ErrNotSupported)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		checker := sniff.New([]string{"image/jpeg"}, true)
+
+		for _, declared := range []string{"image/jpeg", "IMAGE/JPEG; charset=binary"} {
+			_, err := checker.Check(declared, jpeg)
+			require.NoError(t, err, declared)
+		}
+
+		for _, declared := range []string{"application/octet-stream", "", "image/png", "image/jpeg;;"} {
+			_, err := checker.Check(declared, jpeg)
+			require.ErrorIs(t, err, sniff.ErrMismatch, declared)
+		}
+
+		_, err := checker.Check("image/webp", webp)
+		require.ErrorIs(t, err, sniff.ErrNotSupported)
+	})
+}
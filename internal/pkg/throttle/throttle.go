@@ -0,0 +1,258 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxQueue = 100
+
+	// sweepAt the number of hosts that triggers the removal of the idle ones.
+	sweepAt = 1024
+)
+
+var (
+	ErrQueueFull = errors.New("too many requests are waiting for the host")
+	ErrTimeout   = errors.New("timed out waiting for the host")
+)
+
+// Options the zero value limits nothing.
+type Options struct {
+	// MaxConcurrent the number of requests in flight per host, 0 means no limit.
+	MaxConcurrent int
+
+	// Rate the requests per second per host with bursts of Burst requests (at least 1), 0 means no limit.
+	Rate  float64
+	Burst int
+
+	// MaxQueue the number of requests waiting per host, 0 means DefaultMaxQueue and a negative value
+	// rejects the request at once. QueueTimeout limits the wait, 0 waits as long as the context allows.
+	MaxQueue     int
+	QueueTimeout time.Duration
+}
+
+// LimiterInterface release must be called once the request to the host is finished.
+type LimiterInterface interface {
+	Acquire(ctx context.Context, key string) (release func(), err error)
+}
+
+type host struct {
+	slots   chan struct{}
+	waiting int
+	active  int
+
+	tokens float64
+	last   time.Time
+}
+
+type impl struct {
+	mu    sync.Mutex
+	hosts map[string]*host
+
+	options Options
+}
+
+func New(options Options) LimiterInterface {
+	if options.MaxQueue == 0 {
+		options.MaxQueue = DefaultMaxQueue
+	}
+
+	if options.Burst < 1 {
+		options.Burst = 1
+	}
+
+	return &impl{hosts: make(map[string]*host), options: options}
+}
+
+func (l *impl) Acquire(ctx context.Context, key string) (func(), error) {
+	if l.options.QueueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.options.QueueTimeout)
+		defer cancel()
+	}
+
+	h, err := l.enter(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.wait(ctx, h); err != nil {
+		l.leave(key, h, false)
+		return nil, err
+	}
+
+	l.mu.Lock()
+	h.waiting--
+	h.active++
+	l.mu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			l.leave(key, h, true)
+		})
+	}, nil
+}
+
+// enter takes a place in the queue of the host.
+func (l *impl) enter(key string) (*host, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, ok := l.hosts[key]
+	if !ok {
+		if len(l.hosts) >= sweepAt {
+			l.sweep(time.Now())
+		}
+
+		h = &host{tokens: float64(l.options.Burst), last: time.Now()}
+		if l.options.MaxConcurrent > 0 {
+			h.slots = make(chan struct{}, l.options.MaxConcurrent)
+		}
+
+		l.hosts[key] = h
+	}
+
+	if l.options.MaxQueue > 0 && h.waiting >= l.options.MaxQueue {
+		return nil, ErrQueueFull
+	}
+
+	h.waiting++
+
+	return h, nil
+}
+
+// wait for a free slot, then for a token.
+func (l *impl) wait(ctx context.Context, h *host) error {
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		default:
+			if l.options.MaxQueue < 0 {
+				return ErrQueueFull
+			}
+
+			select {
+			case h.slots <- struct{}{}:
+			case <-ctx.Done():
+				return timeout(ctx)
+			}
+		}
+	}
+
+	if err := l.token(ctx, h); err != nil {
+		if h.slots != nil {
+			<-h.slots
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// token reserves a token of the bucket and sleeps until it is available.
+func (l *impl) token(ctx context.Context, h *host) error {
+	if l.options.Rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(h, now)
+	h.tokens--
+	delay := time.Duration(-h.tokens / l.options.Rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	if l.options.MaxQueue < 0 {
+		l.cancel(h)
+		return ErrQueueFull
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now) < delay {
+		l.cancel(h)
+		return ErrTimeout
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(h)
+		return timeout(ctx)
+	}
+}
+
+// cancel returns the reserved token.
+func (l *impl) cancel(h *host) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h.tokens++
+}
+
+func (l *impl) refill(h *host, now time.Time) {
+	h.tokens = math.Min(float64(l.options.Burst), h.tokens+now.Sub(h.last).Seconds()*l.options.Rate)
+	h.last = now
+}
+
+func (l *impl) leave(key string, h *host, active bool) {
+	if active && h.slots != nil {
+		<-h.slots
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if active {
+		h.active--
+	} else {
+		h.waiting--
+	}
+
+	if l.idle(h, time.Now()) {
+		delete(l.hosts, key)
+	}
+}
+
+// idle the host without requests and with a full bucket behaves as a new one.
+func (l *impl) idle(h *host, now time.Time) bool {
+	if h.active > 0 || h.waiting > 0 {
+		return false
+	}
+
+	if l.options.Rate > 0 {
+		l.refill(h, now)
+	}
+
+	return l.options.Rate <= 0 || h.tokens >= float64(l.options.Burst)
+}
+
+func (l *impl) sweep(now time.Time) {
+	for key, h := range l.hosts {
+		if l.idle(h, now) {
+			delete(l.hosts, key)
+		}
+	}
+}
+
+// timeout the own deadline of the queue is reported as ErrTimeout, the cancellation of the request as is.
+func timeout(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,154 @@
+package throttle_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Concurrency(t *testing.T) {
+	limiter := throttle.New(throttle.Options{MaxConcurrent: 2})
+
+	var active, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := limiter.Acquire(context.Background(), "a.com")
+			require.NoError(t, err)
+			defer release()
+
+			current := atomic.AddInt32(&active, 1)
+			for {
+				highest := atomic.LoadInt32(&peak)
+				if current <= highest || atomic.CompareAndSwapInt32(&peak, highest, current) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+
+	wg.Wait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestLimiter_Queue(t *testing.T) {
+	t.Run("full", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{MaxConcurrent: 1, MaxQueue: 1})
+
+		release, err := limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+
+		// the host with the free slot is not affected
+		other, err := limiter.Acquire(context.Background(), "b.com")
+		require.NoError(t, err)
+		other()
+
+		done := make(chan error)
+		go func() {
+			release, err := limiter.Acquire(context.Background(), "a.com")
+			if err == nil {
+				release()
+			}
+			done <- err
+		}()
+
+		// once the goroutine is queued, there is no room left
+		require.Eventually(t, func() bool {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+
+			_, err := limiter.Acquire(ctx, "a.com")
+			return errors.Is(err, throttle.ErrQueueFull)
+		}, time.Second, time.Millisecond)
+
+		release()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("no queue", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{MaxConcurrent: 1, MaxQueue: -1})
+
+		release, err := limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+
+		_, err = limiter.Acquire(context.Background(), "a.com")
+		require.ErrorIs(t, err, throttle.ErrQueueFull)
+
+		release()
+		release()
+
+		release, err = limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{MaxConcurrent: 1, QueueTimeout: 10 * time.Millisecond})
+
+		release, err := limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+		defer release()
+
+		_, err = limiter.Acquire(context.Background(), "a.com")
+		require.ErrorIs(t, err, throttle.ErrTimeout)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{MaxConcurrent: 1})
+
+		release, err := limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = limiter.Acquire(ctx, "a.com")
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	t.Run("burst", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{Rate: 50, Burst: 2})
+
+		now := time.Now()
+		for i := 0; i < 4; i++ {
+			release, err := limiter.Acquire(context.Background(), "a.com")
+			require.NoError(t, err)
+			release()
+		}
+
+		// two requests of the burst, then two more at 20ms each
+		require.GreaterOrEqual(t, time.Since(now), 35*time.Millisecond)
+	})
+
+	t.Run("wait exceeds the timeout", func(t *testing.T) {
+		limiter := throttle.New(throttle.Options{Rate: 1, QueueTimeout: 50 * time.Millisecond})
+
+		release, err := limiter.Acquire(context.Background(), "a.com")
+		require.NoError(t, err)
+		release()
+
+		now := time.Now()
+		_, err = limiter.Acquire(context.Background(), "a.com")
+		require.ErrorIs(t, err, throttle.ErrTimeout)
+		require.Less(t, time.Since(now), 50*time.Millisecond)
+
+		// the rejected request does not take the token
+		_, err = limiter.Acquire(context.Background(), "a.com")
+		require.ErrorIs(t, err, throttle.ErrTimeout)
+	})
+}
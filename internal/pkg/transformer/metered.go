@@ -0,0 +1,31 @@
+package transformer
+
+import (
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+)
+
+// NewMetered observes the duration of each transformation, labeled with the format.
+func NewMetered(format string, transform TransformInterface, histogram metrics.HistogramInterface) TransformInterface {
+	return &metered{format: format, transform: transform, histogram: histogram}
+}
+
+type metered struct {
+	format    string
+	transform TransformInterface
+	histogram metrics.HistogramInterface
+}
+
+func (m *metered) FillCenter(source []byte, width, height int) ([]byte, error) {
+	now := time.Now()
+	defer func() {
+		m.histogram.Observe(time.Since(now).Seconds(), m.format)
+	}()
+
+	return m.transform.FillCenter(source, width, height)
+}
+
+func (m *metered) IsSupported(source []byte) bool {
+	return m.transform.IsSupported(source)
+}
@@ -0,0 +1,28 @@
+package transformer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/transformer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetered_FillCenter(t *testing.T) {
+	registry := metrics.New()
+	histogram := registry.Histogram("transform_duration_seconds", "Duration.", metrics.DefBuckets, "format")
+	transform := transformer.NewStackBy(transformer.NewMetered("text", &text{}, histogram))
+
+	require.True(t, transform.IsSupported([]byte("hello")))
+
+	dst, err := transform.FillCenter([]byte("hello"), 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), dst)
+
+	buffer := &bytes.Buffer{}
+	_, err = registry.WriteTo(buffer)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(buffer.String(), `transform_duration_seconds_count{format="text"} 1`))
+}
@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// ClientIP exposes the client address of the access log to the tests.
+func ClientIP(r *http.Request, trustForwardedFor bool) string {
+	return clientIP(r, trustForwardedFor)
+}
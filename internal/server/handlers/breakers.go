@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
+)
+
+type breakerStatus struct {
+	Host     string     `json:"host"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// Breakers lists the origin hosts with failures, a disabled breaker lists nothing.
+type Breakers struct {
+	Breaker breaker.BreakerInterface
+}
+
+func (b *Breakers) Handle(w http.ResponseWriter, _ *http.Request) {
+	result := []breakerStatus{}
+	if b.Breaker != nil {
+		for _, status := range b.Breaker.Status() {
+			item := breakerStatus{Host: status.Key, State: status.State.String(), Failures: status.Failures}
+			if !status.Until.IsZero() {
+				until := status.Until
+				item.Until = &until
+			}
+
+			result = append(result, item)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
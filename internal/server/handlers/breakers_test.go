@@ -0,0 +1,41 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/server/handlers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakers_Handle(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	hostBreaker := breaker.New(breaker.Options{
+		Threshold: 2,
+		CoolDown:  time.Minute,
+		Now:       func() time.Time { return now },
+	}, logger.Nop())
+
+	for _, host := range []string{"b.com", "b.com", "a.com"} {
+		require.NoError(t, hostBreaker.Allow(host))
+		hostBreaker.Done(host, errors.New("connection refused"))
+	}
+
+	recorder := httptest.NewRecorder()
+	(&handlers.Breakers{Breaker: hostBreaker}).Handle(recorder, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.JSONEq(t, `[
+		{"host": "a.com", "state": "closed", "failures": 1},
+		{"host": "b.com", "state": "open", "failures": 2, "until": "2022-01-01T00:01:00Z"}
+	]`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	(&handlers.Breakers{}).Handle(recorder, httptest.NewRequest(http.MethodGet, "/breakers", nil))
+	require.JSONEq(t, `[]`, recorder.Body.String())
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+)
+
+type Metrics struct {
+	Registry metrics.RegistryInterface
+}
+
+func (m *Metrics) Handle(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.Registry.WriteTo(w)
+}
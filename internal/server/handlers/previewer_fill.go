@@ -1,16 +1,23 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"errors"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/rez1dent3/otus-final/internal/imgprev"
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
 	"github.com/rez1dent3/otus-final/internal/pkg/bytesize"
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
 	"github.com/rez1dent3/otus-final/internal/pkg/fs"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
 	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
 	"github.com/rez1dent3/otus-final/internal/usecases"
 )
 
@@ -20,6 +27,9 @@ type PreviewHandler struct {
 	app     imgprev.AppInterface
 	useCase usecases.PreviewUseCaseInterface
 	cache   lru.CacheInterface
+
+	// StripQuery the query of the request is not passed to the origin.
+	StripQuery bool
 }
 
 func NewPreviewer(app imgprev.AppInterface) *PreviewHandler {
@@ -28,12 +38,16 @@ func NewPreviewer(app imgprev.AppInterface) *PreviewHandler {
 	commandBus := app.CommandBus()
 
 	fm := fs.New(config.Preview.CacheDir, config.Preview.CachePrefix)
-	previewerCache := lru.New(bytesize.Parse(config.Preview.CacheSize), commandBus)
+	if size := bytesize.Parse(config.Preview.MemorySize); size > 0 {
+		fm = fs.NewTiered(fm, lru.NewNamed(imgprev.CachePreviewMemory, size, commandBus))
+	}
+
+	previewerCache := app.PreviewCache()
 
 	commandBus.Subscribe(lru.EventEvict, func(input any) {
-		if val, ok := input.(usecases.PreviewItem); ok {
+		if val, ok := input.(*usecases.PreviewItem); ok {
 			if err := fm.Delete(val.Key); err != nil {
-				app.Logger().Error(err.Error())
+				app.Logger().Error("failed to delete preview", "key", val.Key, "error", err)
 			}
 		}
 	})
@@ -46,7 +60,12 @@ func NewPreviewer(app imgprev.AppInterface) *PreviewHandler {
 		app.Fetcher(),
 	)
 
-	return &PreviewHandler{app: app, useCase: useCase, cache: previewerCache}
+	return &PreviewHandler{
+		app:        app,
+		useCase:    useCase,
+		cache:      previewerCache,
+		StripQuery: config.Source.StripQuery,
+	}
 }
 
 func (p *PreviewHandler) PreviewerFillHandle(
@@ -58,7 +77,8 @@ func (p *PreviewHandler) PreviewerFillHandle(
 ) {
 	resp, err := p.useCase.FillCenter(r.Context(), originalURL, width, height, r.Header)
 	if err != nil {
-		w.WriteHeader(http.StatusBadGateway)
+		p.logger(r).Warning("failed to make preview", "url", originalURL, "error", err)
+		w.WriteHeader(StatusByError(err))
 		return
 	}
 
@@ -66,8 +86,31 @@ func (p *PreviewHandler) PreviewerFillHandle(
 	_, _ = w.Write(resp)
 }
 
+// StatusByError errors of the remote server are reported as 502 Bad Gateway.
+func StatusByError(err error) int {
+	switch {
+	case errors.Is(err, fetcher.ErrHostNotAllowed), errors.Is(err, netguard.ErrBlockedAddress),
+		errors.Is(err, fetcher.ErrBadPath):
+		return http.StatusForbidden
+	case errors.Is(err, fetcher.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, fetcher.ErrNotSupportedScheme):
+		return http.StatusBadRequest
+	case errors.Is(err, fetcher.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, breaker.ErrOpen), errors.Is(err, throttle.ErrQueueFull), errors.Is(err, throttle.ErrTimeout):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
 var reFillRoute = regexp.MustCompile(`^\/fill\/(\d+)\/(\d+)\/(.+)$`)
 
+// EncodedPrefix marks the source url encoded with base64url: /fill/300/200/b64/{encoded}[.ext],
+// so the url keeps its query, fragment and the characters the path can't carry.
+const EncodedPrefix = "b64/"
+
 func (p *PreviewHandler) ParseURL(r *http.Request) (string, int, int, error) {
 	results := reFillRoute.FindStringSubmatch(r.URL.Path)
 	if len(results) != 4 {
@@ -84,12 +127,49 @@ func (p *PreviewHandler) ParseURL(r *http.Request) (string, int, int, error) {
 		return "", 0, 0, err
 	}
 
-	return results[3], width, height, nil
+	originalURL := results[3]
+	if strings.HasPrefix(originalURL, EncodedPrefix) {
+		if originalURL, err = decodeURL(strings.TrimPrefix(originalURL, EncodedPrefix)); err != nil {
+			return "", 0, 0, err
+		}
+	}
+
+	return originalURL, width, height, nil
+}
+
+// Query the query of the request belongs to the http source url: signed urls and cache busting.
+// It is passed apart from the url, so the other sources don't take it for a part of the path,
+// the encoded url carries its own query.
+func (p *PreviewHandler) Query(r *http.Request) string {
+	if p.StripQuery {
+		return ""
+	}
+
+	if results := reFillRoute.FindStringSubmatch(r.URL.Path); len(results) == 4 &&
+		strings.HasPrefix(results[3], EncodedPrefix) {
+		return ""
+	}
+
+	return r.URL.RawQuery
+}
+
+// decodeURL the extension is only a hint for the client, the padding is optional.
+func decodeURL(encoded string) (string, error) {
+	if dot := strings.IndexByte(encoded, '.'); dot >= 0 {
+		encoded = encoded[:dot]
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(decoded) == 0 {
+		return "", ErrParseURL
+	}
+
+	return string(decoded), nil
 }
 
 func (p *PreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		p.app.Logger().Info("Method not allowed")
+		p.logger(r).Info("method not allowed", "method", r.Method)
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		_, _ = w.Write([]byte("Method not allowed"))
 		return
@@ -97,12 +177,17 @@ func (p *PreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	originalURL, width, height, err := p.ParseURL(r)
 	if err != nil {
-		p.app.Logger().Info(err.Error())
+		p.logger(r).Info("failed to parse url", "path", r.URL.Path, "error", err)
 		http.NotFound(w, r)
 		return
 	}
 
-	p.PreviewerFillHandle(originalURL, width, height, w, r)
+	ctx := fetcher.WithQuery(r.Context(), p.Query(r))
+	p.PreviewerFillHandle(originalURL, width, height, w, r.WithContext(ctx))
+}
+
+func (p *PreviewHandler) logger(r *http.Request) logger.LogInterface {
+	return logger.FromContext(r.Context(), p.app.Logger())
 }
 
 func (p *PreviewHandler) Purge() {
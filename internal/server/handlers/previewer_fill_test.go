@@ -1,11 +1,21 @@
 package handlers_test
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
 
+	"github.com/rez1dent3/otus-final/internal/imgprev"
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
 	"github.com/rez1dent3/otus-final/internal/server/handlers"
+	"github.com/rez1dent3/otus-final/internal/usecases"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,4 +30,114 @@ func TestPreviewHandler_ParseURL(t *testing.T) {
 		require.Equal(t, 100, width)
 		require.Equal(t, 100, height)
 	})
+
+	t.Run("query", func(t *testing.T) {
+		request := &http.Request{URL: &url.URL{Path: "/fill/300/200/cdn.example.com/img.jpg", RawQuery: "v=3&token=a%2Bb"}}
+
+		// the query is passed apart from the url
+		ph := handlers.PreviewHandler{}
+		originalURL, _, _, err := ph.ParseURL(request)
+		require.NoError(t, err)
+		require.Equal(t, "cdn.example.com/img.jpg", originalURL)
+		require.Equal(t, "v=3&token=a%2Bb", ph.Query(request))
+
+		ph = handlers.PreviewHandler{StripQuery: true}
+		require.Empty(t, ph.Query(request))
+	})
+
+	t.Run("encoded query", func(t *testing.T) {
+		encoded := base64.RawURLEncoding.EncodeToString([]byte("cdn.example.com/img.jpg?v=3"))
+		request := &http.Request{URL: &url.URL{Path: "/fill/300/200/b64/" + encoded, RawQuery: "v=4"}}
+
+		ph := handlers.PreviewHandler{}
+		originalURL, _, _, err := ph.ParseURL(request)
+		require.NoError(t, err)
+		require.Equal(t, "cdn.example.com/img.jpg?v=3", originalURL)
+		require.Empty(t, ph.Query(request))
+	})
+
+	t.Run("encoded", func(t *testing.T) {
+		testCases := []string{
+			"https://example.com/img.jpg?size=large&v=2",
+			"https://example.com/img.jpg#preview",
+			"https://example.com/my images/cat photo.jpg",
+			"https://пример.рф/картинки/кот.jpg?имя=кот",
+			"data:image/gif;base64,R0lGODlhAQABAAAAACw=",
+		}
+
+		for _, source := range testCases {
+			encoded := base64.RawURLEncoding.EncodeToString([]byte(source))
+
+			for _, path := range []string{
+				"/fill/300/200/b64/" + encoded,
+				"/fill/300/200/b64/" + encoded + ".jpg",
+				"/fill/300/200/b64/" + base64.URLEncoding.EncodeToString([]byte(source)),
+			} {
+				ph := handlers.PreviewHandler{}
+				originalURL, width, height, err := ph.ParseURL(&http.Request{URL: &url.URL{Path: path}})
+				require.NoError(t, err, path)
+				require.Equal(t, source, originalURL, path)
+				require.Equal(t, 300, width)
+				require.Equal(t, 200, height)
+			}
+		}
+	})
+
+	t.Run("bad encoding", func(t *testing.T) {
+		for _, path := range []string{"/fill/300/200/b64/", "/fill/300/200/b64/!!!", "/fill/300/200/b64/.jpg"} {
+			ph := handlers.PreviewHandler{}
+			_, _, _, err := ph.ParseURL(&http.Request{URL: &url.URL{Path: path}})
+			require.ErrorIs(t, err, handlers.ErrParseURL, path)
+		}
+	})
+}
+
+func TestStatusByError(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("unknown"), http.StatusBadGateway},
+		{fmt.Errorf("prepare: %w", fetcher.ErrNotSupportedContentType), http.StatusBadGateway},
+		{fmt.Errorf("prepare: %w", fetcher.ErrHostNotAllowed), http.StatusForbidden},
+		{fmt.Errorf("dial: %w", netguard.ErrBlockedAddress), http.StatusForbidden},
+		{fmt.Errorf("read: %w", fetcher.ErrTooLarge), http.StatusRequestEntityTooLarge},
+		{fmt.Errorf("open: %w", fetcher.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("ftp://a.com: %w", fetcher.ErrNotSupportedScheme), http.StatusBadRequest},
+		{fmt.Errorf("local/img/../a.jpg: %w", fetcher.ErrBadPath), http.StatusForbidden},
+		{fmt.Errorf("a.com: %w", breaker.ErrOpen), http.StatusServiceUnavailable},
+		{fmt.Errorf("a.com: %w", throttle.ErrTimeout), http.StatusServiceUnavailable},
+		{fmt.Errorf("a.com: %w", throttle.ErrQueueFull), http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.status, handlers.StatusByError(testCase.err), testCase.err.Error())
+	}
+}
+
+func TestNewPreviewer_Evict(t *testing.T) {
+	config := &imgprev.Config{}
+	config.Original.CacheDir = t.TempDir()
+	config.Original.CachePrefix = "original"
+	config.Original.CacheSize = "1M"
+	config.Preview.CacheDir = t.TempDir()
+	config.Preview.CachePrefix = "preview"
+	config.Preview.CacheSize = "1M"
+
+	app, err := imgprev.New(config)
+	require.NoError(t, err)
+
+	defer app.Close()
+
+	handlers.NewPreviewer(app)
+
+	fm := fs.New(config.Preview.CacheDir, config.Preview.CachePrefix)
+	require.NoError(t, fm.Create("key", []byte("preview")))
+	require.True(t, app.PreviewCache().Put("key", &usecases.PreviewItem{Key: "key"}))
+
+	// the evicted preview is deleted from the disk
+	require.True(t, app.PreviewCache().Remove("key"))
+
+	_, err = fm.Content("key")
+	require.ErrorIs(t, err, fs.ErrOpenFile)
 }
@@ -2,11 +2,19 @@ package server
 
 import (
 	"context"
-	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rez1dent3/otus-final/internal/imgprev"
+	"github.com/rez1dent3/otus-final/internal/pkg/accesslog"
+	"github.com/rez1dent3/otus-final/internal/pkg/cachestat"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/requestid"
 	"github.com/rez1dent3/otus-final/internal/server/handlers"
 )
 
@@ -18,12 +26,30 @@ type HTTPServerInterface interface {
 type impl struct {
 	app    imgprev.AppInterface
 	server *http.Server
+	mux    *http.ServeMux
 
 	previewer *handlers.PreviewHandler
+
+	accessLog accesslog.LogInterface
+
+	requests metrics.CounterInterface
+	latency  metrics.HistogramInterface
 }
 
 func New(appImpl imgprev.AppInterface) HTTPServerInterface {
-	return &impl{app: appImpl, previewer: handlers.NewPreviewer(appImpl)}
+	registry := appImpl.Metrics()
+	config := appImpl.Config().Logger.AccessLog
+
+	return &impl{
+		app:       appImpl,
+		previewer: handlers.NewPreviewer(appImpl),
+		accessLog: accesslog.New(config.Format, config.SampleRate, appImpl.Logger(), os.Stdout),
+
+		requests: registry.Counter("imgproxy_http_requests_total",
+			"Handled HTTP requests.", "route", "code"),
+		latency: registry.Histogram("imgproxy_http_request_duration_seconds",
+			"Latency of the handled HTTP requests.", metrics.DefBuckets, "route", "code"),
+	}
 }
 
 func (i *impl) ListenAndServe(ctx context.Context) error {
@@ -40,7 +66,7 @@ func (i *impl) ListenAndServe(ctx context.Context) error {
 
 		err := i.Stop(ctx)
 		if err != nil {
-			i.app.Logger().Error(err.Error())
+			i.app.Logger().Error("failed to stop http server", "error", err)
 			return
 		}
 	}()
@@ -53,6 +79,8 @@ func (i *impl) Stop(ctx context.Context) error {
 		i.previewer.Purge()
 	}
 
+	i.app.Close()
+
 	if i.server == nil {
 		return nil
 	}
@@ -61,28 +89,84 @@ func (i *impl) Stop(ctx context.Context) error {
 }
 
 func (i *impl) HTTPHandler() http.Handler {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", (&handlers.Health{}).Handle)
-	mux.Handle("/fill/", i.previewer)
+	i.mux = http.NewServeMux()
+	i.mux.HandleFunc("/health", (&handlers.Health{}).Handle)
+	i.mux.HandleFunc("/metrics", (&handlers.Metrics{Registry: i.app.Metrics()}).Handle)
+	i.mux.HandleFunc("/breakers", (&handlers.Breakers{Breaker: i.app.Breaker()}).Handle)
+	i.mux.Handle("/fill/", i.previewer)
 
-	return mux
+	return i.mux
+}
+
+// route the pattern of the matched handler, so the metrics do not depend on the image urls.
+func (i *impl) route(r *http.Request) string {
+	if i.mux != nil {
+		if _, pattern := i.mux.Handler(r); pattern != "" {
+			return pattern
+		}
+	}
+
+	return "other"
 }
 
 func (i *impl) middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := i.requestID(r)
+		w.Header().Set(requestid.Header, id)
+
+		log := i.app.Logger().With("request_id", id)
+		ctx := logger.WithContext(requestid.WithContext(r.Context(), id), log)
+		ctx, cache := cachestat.WithRecorder(ctx)
+		rw := accesslog.NewResponseWriter(w, cache)
+
 		now := time.Now()
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r.WithContext(ctx))
 		latency := time.Since(now)
 
-		i.app.Logger().Info(fmt.Sprintf(
-			"%s [%s] %s %s %s %d %s",
-			r.Header.Get("X-Forwarded-For"),
-			now.Format("02/Jan/2006:15:04:05 -0700"),
-			r.Method,
-			r.RequestURI,
-			r.Proto,
-			latency.Microseconds(),
-			r.Header.Get("User-Agent"),
-		))
+		route, code := i.route(r), strconv.Itoa(rw.Status())
+		i.requests.Inc(route, code)
+		i.latency.Observe(latency.Seconds(), route, code)
+
+		i.accessLog.Log(accesslog.Entry{
+			Time:      now,
+			RequestID: id,
+			ClientIP:  clientIP(r, i.app.Config().Server.TrustForwardedFor),
+			Method:    r.Method,
+			URI:       r.RequestURI,
+			Proto:     r.Proto,
+			Status:    rw.Status(),
+			Bytes:     rw.Bytes(),
+			Latency:   latency,
+			Cache:     rw.Cache(),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+		})
 	})
 }
+
+// requestID the incoming id is used only if it is trusted and looks sane.
+func (i *impl) requestID(r *http.Request) string {
+	if id := r.Header.Get(requestid.Header); i.app.Config().Server.TrustRequestID && requestid.Valid(id) {
+		return id
+	}
+
+	return requestid.Generate()
+}
+
+// clientIP the trusted balancer appends the address it sees to X-Forwarded-For, the ones before it come
+// from the client and can be forged.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if forwarded := r.Header.Values("X-Forwarded-For"); trustForwardedFor && len(forwarded) > 0 {
+		addresses := strings.Split(forwarded[len(forwarded)-1], ",")
+		if last := strings.TrimSpace(addresses[len(addresses)-1]); last != "" {
+			return last
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
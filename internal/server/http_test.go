@@ -0,0 +1,30 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		forwarded []string
+		trust     bool
+		expected  string
+	}{
+		{nil, false, "10.0.0.1"},
+		{nil, true, "10.0.0.1"},
+		{[]string{"1.1.1.1"}, false, "10.0.0.1"},
+		{[]string{"1.1.1.1"}, true, "1.1.1.1"},
+		{[]string{"6.6.6.6, 1.1.1.1"}, true, "1.1.1.1"},
+		{[]string{"6.6.6.6", "1.1.1.1"}, true, "1.1.1.1"},
+		{[]string{""}, true, "10.0.0.1"},
+	}
+
+	for _, testCase := range testCases {
+		r := &http.Request{RemoteAddr: "10.0.0.1:50000", Header: http.Header{"X-Forwarded-For": testCase.forwarded}}
+		require.Equal(t, testCase.expected, server.ClientIP(r, testCase.trust), testCase.forwarded)
+	}
+}
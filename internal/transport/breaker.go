@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
+)
+
+// hop a single request to the origin, guarded by the circuit breaker of the host.
+// The retries of a request count as one failure.
+func (t *HTTPTransport) hop(req *http.Request) (*http.Response, error) {
+	if t.options.Breaker == nil {
+		return t.retry(req)
+	}
+
+	host := req.URL.Host
+	if err := t.options.Breaker.Allow(host); err != nil {
+		return nil, fmt.Errorf("%s: %w", host, err)
+	}
+
+	resp, err := t.retry(req)
+	if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+		t.options.Breaker.Done(host, fmt.Errorf("%s: %w", resp.Status, ErrServerError))
+	} else {
+		t.options.Breaker.Done(host, err)
+	}
+
+	return resp, err
+}
+
+// IsFailure the errors that count against the origin host: neither a client that went away,
+// a blocked destination nor our own limits say anything about the health of the host.
+func IsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, netguard.ErrBlockedAddress) &&
+		!errors.Is(err, throttle.ErrQueueFull) &&
+		!errors.Is(err, throttle.ErrTimeout)
+}
@@ -0,0 +1,80 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
+	"github.com/rez1dent3/otus-final/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransport_Breaker(t *testing.T) {
+	var attempts, status int32 = 0, http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		if code := atomic.LoadInt32(&status); code != http.StatusOK {
+			w.WriteHeader(int(code))
+			return
+		}
+
+		http.ServeFile(w, r, "../../resources/images/_gopher_original_1024x504.jpg")
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	hostBreaker := breaker.New(breaker.Options{
+		Threshold: 2,
+		CoolDown:  time.Minute,
+		IsFailure: transport.IsFailure,
+		Now:       func() time.Time { return now },
+	}, logger.Nop())
+
+	httpTransport, _ := newRedirectTransport(t, transport.Options{
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+		Breaker:      hostBreaker,
+	})
+
+	// the retries of a request are a single failure
+	for i := 0; i < 2; i++ {
+		_, err := get(t, httpTransport, server.URL, http.Header{})
+		require.ErrorIs(t, err, transport.ErrServerError)
+	}
+
+	require.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+
+	_, err := get(t, httpTransport, server.URL, http.Header{})
+	require.ErrorIs(t, err, breaker.ErrOpen)
+	require.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+
+	// the probe after the cool-down closes the circuit
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&status, http.StatusOK)
+
+	_, err = get(t, httpTransport, server.URL, http.Header{})
+	require.NoError(t, err)
+	require.Empty(t, hostBreaker.Status())
+
+	// the cached original is served while the circuit is open
+	atomic.StoreInt32(&status, http.StatusBadGateway)
+	for i := 0; i < 2; i++ {
+		_, err = get(t, httpTransport, server.URL+"/other", http.Header{})
+		require.ErrorIs(t, err, transport.ErrServerError)
+	}
+
+	_, err = get(t, httpTransport, server.URL, http.Header{})
+	require.NoError(t, err)
+}
+
+func TestIsFailure(t *testing.T) {
+	require.True(t, transport.IsFailure(transport.ErrServerError))
+	require.False(t, transport.IsFailure(context.Canceled))
+	require.False(t, transport.IsFailure(throttle.ErrTimeout))
+}
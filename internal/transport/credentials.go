@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+// Credential is sent only to the hosts matching Hosts, the first matching credential wins.
+// Every request is authorized on its own, so a redirect to another host never carries it.
+type Credential struct {
+	Hosts hostmatch.MatcherInterface
+
+	// Authorization the value of the header, see BasicAuth and BearerAuth. Empty sends none.
+	Authorization string
+
+	// Certificate the client certificate of the TLS handshake, nil sends none.
+	Certificate *tls.Certificate
+}
+
+func BasicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func BearerAuth(token string) string {
+	return "Bearer " + token
+}
+
+type credential struct {
+	Credential
+
+	// inner the connections with the client certificate are not shared with other hosts.
+	inner *http.Transport
+}
+
+func newCredentials(options Options) []credential {
+	result := make([]credential, 0, len(options.Credentials))
+	for _, c := range options.Credentials {
+		item := credential{Credential: c}
+		if c.Certificate != nil {
+			item.inner = newInner(options)
+			item.inner.TLSClientConfig = &tls.Config{
+				Certificates: []tls.Certificate{*c.Certificate},
+				MinVersion:   tls.VersionTLS12,
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// authorize returns the transport and the request with the credential of the host.
+func (t *HTTPTransport) authorize(req *http.Request) (http.RoundTripper, *http.Request) {
+	for _, c := range t.credentials {
+		if !c.Hosts.Match(req.URL.Host) {
+			continue
+		}
+
+		if c.Authorization != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", c.Authorization)
+		}
+
+		if c.inner != nil {
+			return c.inner, req
+		}
+
+		break
+	}
+
+	return t.inner, req
+}
@@ -0,0 +1,128 @@
+package transport_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/rez1dent3/otus-final/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+const image = "../../resources/images/_gopher_original_1024x504.jpg"
+
+func matcher(t *testing.T, patterns ...string) hostmatch.MatcherInterface {
+	t.Helper()
+
+	m, err := hostmatch.New(patterns)
+	require.NoError(t, err)
+
+	return m
+}
+
+func clientCertificate(t *testing.T) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "imgproxy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestHTTPTransport_Credentials(t *testing.T) {
+	t.Run("authorization", func(t *testing.T) {
+		var leaked []string
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			leaked = append(leaked, r.Header.Get("Authorization"))
+			http.ServeFile(w, r, image)
+		}))
+		defer other.Close()
+
+		// the same server under another host name
+		otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+		origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != transport.BasicAuth("user", "secret") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if r.URL.Path == "/away" {
+				http.Redirect(w, r, otherURL+"/image.jpg", http.StatusFound)
+				return
+			}
+
+			http.ServeFile(w, r, image)
+		}))
+		defer origin.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			MaxRedirects: 1,
+			Credentials: []transport.Credential{
+				{Hosts: matcher(t, "127.0.0.1"), Authorization: transport.BasicAuth("user", "secret")},
+				{Hosts: matcher(t, "*"), Authorization: transport.BearerAuth("never")},
+			},
+		})
+
+		_, err := get(t, httpTransport, origin.URL+"/image.jpg", http.Header{"Authorization": []string{"from-client"}})
+		require.NoError(t, err)
+
+		_, err = get(t, httpTransport, origin.URL+"/away", http.Header{})
+		require.NoError(t, err)
+
+		// the redirect target gets its own credential, never the one of the origin
+		require.Equal(t, []string{transport.BearerAuth("never")}, leaked)
+	})
+
+	t.Run("client certificate", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, image)
+		}))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+		server.StartTLS()
+		defer server.Close()
+
+		trusted := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+		withCertificate, _ := newRedirectTransport(t, transport.Options{
+			Credentials: []transport.Credential{
+				{Hosts: matcher(t, "127.0.0.1"), Certificate: clientCertificate(t)},
+			},
+		})
+		transport.SetTLSConfig(withCertificate, trusted)
+
+		_, err := get(t, withCertificate, server.URL+"/a.jpg", http.Header{})
+		require.NoError(t, err)
+
+		withoutCertificate, _ := newRedirectTransport(t, transport.Options{
+			Credentials: []transport.Credential{
+				{Hosts: matcher(t, "example.com"), Certificate: clientCertificate(t)},
+			},
+		})
+		transport.SetTLSConfig(withoutCertificate, trusted)
+
+		_, err = get(t, withoutCertificate, server.URL+"/b.jpg", http.Header{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,24 @@
+package transport
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// SetTLSConfig lets the tests trust the certificate of httptest.NewTLSServer.
+func SetTLSConfig(t *HTTPTransport, config *tls.Config) {
+	t.inner.TLSClientConfig = config
+
+	for _, c := range t.credentials {
+		if c.inner != nil {
+			withCertificate := config.Clone()
+			withCertificate.Certificates = c.inner.TLSClientConfig.Certificates
+			c.inner.TLSClientConfig = withCertificate
+		}
+	}
+}
+
+// SetJitter replaces the random delay before a retry.
+func SetJitter(t *HTTPTransport, jitter func(time.Duration) time.Duration) {
+	t.jitter = jitter
+}
@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+)
+
+var (
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrBadRedirect      = errors.New("redirect location is not allowed")
+)
+
+// sensitiveHeaders are not sent to another host, the same as http.Client does.
+var sensitiveHeaders = []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"}
+
+func isRedirect(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return resp.Header.Get("Location") != ""
+	default:
+		return false
+	}
+}
+
+// roundTrip follows the redirects up to the limit, the final url is in resp.Request.
+func (t *HTTPTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	for redirects := 0; ; redirects++ {
+		resp, err := t.hop(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Request == nil {
+			resp.Request = req
+		}
+
+		if !isRedirect(resp) || t.options.MaxRedirects == 0 {
+			return resp, nil
+		}
+
+		location := resp.Header.Get("Location")
+
+		// read a bit of the body, so the connection can be reused
+		_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+		_ = resp.Body.Close()
+
+		if redirects >= t.options.MaxRedirects {
+			return nil, fmt.Errorf("%d: %w", redirects, ErrTooManyRedirects)
+		}
+
+		req, err = t.redirect(req, location)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *HTTPTransport) redirect(req *http.Request, location string) (*http.Request, error) {
+	target, err := req.URL.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", location, ErrBadRedirect)
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("%s: %w", target.Redacted(), ErrBadRedirect)
+	}
+
+	if t.options.ForbidDowngrade && req.URL.Scheme == "https" && target.Scheme == "http" {
+		return nil, fmt.Errorf("downgrade to %s: %w", target.Redacted(), ErrBadRedirect)
+	}
+
+	if t.options.Hosts != nil && !t.options.Hosts.Allowed(target.Host) {
+		return nil, fmt.Errorf("redirect to %s: %w", target.Hostname(), hostmatch.ErrNotAllowed)
+	}
+
+	t.logger(req).Debug("following redirect", "url", req.URL.String(), "location", target.String())
+
+	next := req.Clone(req.Context())
+	next.URL = target
+	next.Host = ""
+
+	if !strings.EqualFold(req.URL.Hostname(), target.Hostname()) {
+		for _, header := range sensitiveHeaders {
+			next.Header.Del(header)
+		}
+	}
+
+	return next, nil
+}
@@ -0,0 +1,191 @@
+package transport_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func redirectServer(hits *int32) *httptest.Server {
+	files := http.FileServer(http.Dir("../../resources/images"))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/_gopher_original_1024x504.jpg", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		files.ServeHTTP(w, r)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newRedirectTransport(
+	t *testing.T,
+	options transport.Options,
+) (*transport.HTTPTransport, lru.CacheInterface) {
+	t.Helper()
+
+	hash := hsum.New()
+	fm := fs.New(os.TempDir(), "transport-redirect-test")
+	cache := newCache(hash, fm)
+	t.Cleanup(cache.Purge)
+
+	return transport.New(hash, cache, fm, logger.New("off", nil), metrics.New(), options), cache
+}
+
+func get(t *testing.T, roundTripper http.RoundTripper, rawURL string, header http.Header) ([]byte, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, rawURL, nil)
+	require.NoError(t, err)
+	req.Header = header
+
+	client := http.Client{Transport: roundTripper, Timeout: time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	return io.ReadAll(resp.Body)
+}
+
+func TestHTTPTransport_Redirect(t *testing.T) {
+	t.Run("follow and cache by location", func(t *testing.T) {
+		var hits int32
+		server := redirectServer(&hits)
+		defer server.Close()
+
+		httpTransport, cache := newRedirectTransport(t, transport.Options{MaxRedirects: 3})
+
+		body, err := get(t, httpTransport, server.URL+"/redirect", http.Header{})
+		require.NoError(t, err)
+		require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+
+		require.True(t, cache.Has(server.URL+"/redirect"))
+		require.True(t, cache.Has(server.URL+"/_gopher_original_1024x504.jpg"))
+
+		// both the redirecting and the final url are served from the cache
+		for _, path := range []string{"/redirect", "/_gopher_original_1024x504.jpg"} {
+			body, err = get(t, httpTransport, server.URL+path, http.Header{})
+			require.NoError(t, err)
+			require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		var hits int32
+		server := redirectServer(&hits)
+		defer server.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{})
+
+		_, err := get(t, httpTransport, server.URL+"/redirect", http.Header{})
+		require.ErrorIs(t, err, transport.ErrServerError)
+		require.Equal(t, int32(0), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("too many redirects", func(t *testing.T) {
+		var hits int32
+		server := redirectServer(&hits)
+		defer server.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{MaxRedirects: 3})
+
+		_, err := get(t, httpTransport, server.URL+"/loop", http.Header{})
+		require.ErrorIs(t, err, transport.ErrTooManyRedirects)
+	})
+
+	t.Run("host policy", func(t *testing.T) {
+		var hits int32
+		server := redirectServer(&hits)
+		defer server.Close()
+
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, server.URL+"/_gopher_original_1024x504.jpg", http.StatusFound)
+		}))
+		defer redirector.Close()
+
+		hosts, err := hostmatch.NewPolicy(nil, []string{"127.0.0.1"})
+		require.NoError(t, err)
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{MaxRedirects: 3, Hosts: hosts})
+
+		// the transport checks only the redirect targets, the first url is checked by the fetcher
+		_, err = get(t, httpTransport, redirector.URL, http.Header{})
+		require.ErrorIs(t, err, hostmatch.ErrNotAllowed)
+		require.Equal(t, int32(0), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("credentials are not sent to another host", func(t *testing.T) {
+		var authorization atomic.Value
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization") + "|" + r.Header.Get("User-Agent"))
+			http.ServeFile(w, r, "../../resources/images/_gopher_original_1024x504.jpg")
+		}))
+		defer target.Close()
+
+		port := strconv.Itoa(target.Listener.Addr().(*net.TCPAddr).Port)
+		redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://localhost:"+port+"/a.jpg", http.StatusTemporaryRedirect)
+		}))
+		defer redirector.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{MaxRedirects: 3})
+
+		_, err := get(t, httpTransport, redirector.URL, http.Header{
+			"Authorization": []string{"Basic dXNlcjp1c2Vy"},
+			"User-Agent":    []string{"test"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "|test", authorization.Load())
+	})
+
+	t.Run("downgrade", func(t *testing.T) {
+		var hits int32
+		server := redirectServer(&hits)
+		defer server.Close()
+
+		secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, server.URL+"/_gopher_original_1024x504.jpg", http.StatusFound)
+		}))
+		defer secure.Close()
+
+		for _, forbid := range []bool{true, false} {
+			httpTransport, _ := newRedirectTransport(t, transport.Options{MaxRedirects: 3, ForbidDowngrade: forbid})
+			transport.SetTLSConfig(httpTransport, secure.Client().Transport.(*http.Transport).TLSClientConfig)
+
+			_, err := get(t, httpTransport, secure.URL, http.Header{})
+			if forbid {
+				require.ErrorIs(t, err, transport.ErrBadRedirect)
+			} else {
+				require.NoError(t, err)
+			}
+		}
+	})
+}
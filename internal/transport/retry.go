@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+)
+
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+)
+
+// retry the request to the origin on the transient failures.
+func (t *HTTPTransport) retry(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		if attempt >= t.options.Retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		t.logger(req).Debug("retrying origin request", "url", req.URL.String(), "attempt", attempt+1, "delay", delay)
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable connection failures and the gateway errors of idempotent requests.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if err != nil {
+		if errors.Is(err, netguard.ErrBlockedAddress) || req.Context().Err() != nil {
+			return false
+		}
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false
+		}
+
+		var opErr *net.OpError
+
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff exponential with the jitter.
+func (t *HTTPTransport) backoff(attempt int) time.Duration {
+	base, limit := t.options.RetryBackoff, t.options.RetryMaxBackoff
+	if base <= 0 {
+		base = defaultRetryBackoff
+	}
+
+	if limit <= 0 {
+		limit = defaultRetryMaxBackoff
+	}
+
+	delay := base
+	for i := 0; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+
+	if delay > limit {
+		delay = limit
+	}
+
+	return t.jitter(delay)
+}
+
+// fullJitter a random delay up to the backoff, so the clients of a failed origin don't retry together.
+func fullJitter(delay time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(delay)) + 1) //nolint:gosec
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
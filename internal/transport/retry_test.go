@@ -0,0 +1,157 @@
+package transport_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer fails with the status the given number of times, then serves the image.
+func flakyServer(status int, failures int32, attempts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(attempts, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+
+		http.ServeFile(w, r, "../../resources/images/_gopher_original_1024x504.jpg")
+	}))
+}
+
+func TestHTTPTransport_Retry(t *testing.T) {
+	options := transport.Options{Retries: 2, RetryBackoff: time.Millisecond, RetryMaxBackoff: 5 * time.Millisecond}
+
+	t.Run("gateway errors", func(t *testing.T) {
+		for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+			var attempts int32
+			server := flakyServer(status, 2, &attempts)
+
+			httpTransport, _ := newRedirectTransport(t, options)
+			body, err := get(t, httpTransport, server.URL, http.Header{})
+			server.Close()
+
+			require.NoError(t, err)
+			require.Equal(t, "f14b75d39d77d92b", hsum.New().Hash(body))
+			require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var attempts int32
+		server := flakyServer(http.StatusServiceUnavailable, 10, &attempts)
+		defer server.Close()
+
+		httpTransport, _ := newRedirectTransport(t, options)
+		_, err := get(t, httpTransport, server.URL, http.Header{})
+
+		require.ErrorIs(t, err, transport.ErrServerError)
+		require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		for _, status := range []int{http.StatusInternalServerError, http.StatusNotFound, http.StatusTooManyRequests} {
+			var attempts int32
+			server := flakyServer(status, 1, &attempts)
+
+			httpTransport, _ := newRedirectTransport(t, options)
+			_, err := get(t, httpTransport, server.URL, http.Header{})
+			server.Close()
+
+			require.ErrorIs(t, err, transport.ErrServerError)
+			require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+		}
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			Retries:      2,
+			RetryBackoff: 20 * time.Millisecond,
+		})
+		delays := noJitter(httpTransport)
+
+		now := time.Now()
+		_, err = get(t, httpTransport, "http://"+addr, http.Header{})
+		require.Error(t, err)
+
+		// three attempts with the exponential delays between them
+		require.Equal(t, []time.Duration{20 * time.Millisecond, 40 * time.Millisecond}, *delays)
+		require.GreaterOrEqual(t, time.Since(now), 60*time.Millisecond)
+	})
+
+	t.Run("max backoff", func(t *testing.T) {
+		var attempts int32
+		server := flakyServer(http.StatusServiceUnavailable, 10, &attempts)
+		defer server.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			Retries:         4,
+			RetryBackoff:    time.Millisecond,
+			RetryMaxBackoff: 3 * time.Millisecond,
+		})
+		delays := noJitter(httpTransport)
+
+		_, err := get(t, httpTransport, server.URL, http.Header{})
+		require.ErrorIs(t, err, transport.ErrServerError)
+
+		require.Equal(t, int32(5), atomic.LoadInt32(&attempts))
+		require.Equal(t, []time.Duration{
+			time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond,
+		}, *delays)
+	})
+
+	t.Run("respects the deadline", func(t *testing.T) {
+		var attempts int32
+		server := flakyServer(http.StatusServiceUnavailable, 10, &attempts)
+		defer server.Close()
+
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			Retries:         5,
+			RetryBackoff:    50 * time.Millisecond,
+			RetryMaxBackoff: time.Second,
+		})
+		delays := noJitter(httpTransport)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		// the attempts at 0, 50ms, 150ms and 350ms, the next delay of 400ms ends past the deadline
+		now := time.Now()
+		resp, err := httpTransport.RoundTrip(req) //nolint:bodyclose
+		require.Error(t, err)
+		require.Nil(t, resp)
+		require.Less(t, time.Since(now), 500*time.Millisecond)
+
+		require.Equal(t, int32(4), atomic.LoadInt32(&attempts))
+		require.Equal(t, []time.Duration{
+			50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		}, *delays)
+	})
+}
+
+// noJitter the delays are the backoff itself, the list of them is returned.
+func noJitter(httpTransport *transport.HTTPTransport) *[]time.Duration {
+	var delays []time.Duration
+	transport.SetJitter(httpTransport, func(delay time.Duration) time.Duration {
+		delays = append(delays, delay)
+
+		return delay
+	})
+
+	return &delays
+}
@@ -0,0 +1,66 @@
+package transport_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
+	"github.com/rez1dent3/otus-final/internal/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransport_Limiter(t *testing.T) {
+	var active, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		for {
+			highest := atomic.LoadInt32(&peak)
+			if current <= highest || atomic.CompareAndSwapInt32(&peak, highest, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		http.ServeFile(w, r, "../../resources/images/_gopher_original_1024x504.jpg")
+	}))
+	defer server.Close()
+
+	t.Run("concurrency", func(t *testing.T) {
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			Limiter: throttle.New(throttle.Options{MaxConcurrent: 1}),
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				_, err := get(t, httpTransport, fmt.Sprintf("%s/%d", server.URL, i), http.Header{})
+				require.NoError(t, err)
+			}(i)
+		}
+
+		wg.Wait()
+		require.Equal(t, int32(1), atomic.LoadInt32(&peak))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		httpTransport, _ := newRedirectTransport(t, transport.Options{
+			Limiter: throttle.New(throttle.Options{Rate: 1, MaxQueue: -1}),
+		})
+
+		_, err := get(t, httpTransport, server.URL+"/a", http.Header{})
+		require.NoError(t, err)
+
+		_, err = get(t, httpTransport, server.URL+"/b", http.Header{})
+		require.ErrorIs(t, err, throttle.ErrQueueFull)
+	})
+}
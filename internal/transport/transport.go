@@ -5,32 +5,101 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/rez1dent3/otus-final/internal/pkg/breaker"
 	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
 	"github.com/rez1dent3/otus-final/internal/pkg/logger"
 	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
+	"github.com/rez1dent3/otus-final/internal/pkg/throttle"
 )
 
 var ErrServerError = errors.New("server error")
 
+// ResponseItem the content of URL is stored in a file, unless URL redirects to Location:
+// then the content is cached under Location and the item is only a link to it.
 type ResponseItem struct {
-	URL  string
-	size uint64
+	URL         string
+	Location    string
+	ContentType string
+	size        uint64
 }
 
 func (i ResponseItem) Size() uint64 {
 	return i.size
 }
 
+// HasContent reports whether there is a file to delete on evict.
+func (i ResponseItem) HasContent() bool {
+	return i.Location == ""
+}
+
+// Options network settings, the zero value keeps the defaults of http.Transport.
+type Options struct {
+	// Guard checks every address the transport connects to.
+	Guard netguard.GuardInterface
+
+	// Hosts is checked for every redirect target, nil allows any host.
+	Hosts hostmatch.PolicyInterface
+
+	// MaxRedirects the number of redirects to follow, 0 returns the redirect response as is.
+	MaxRedirects int
+
+	// ForbidDowngrade do not follow redirects from https to http.
+	ForbidDowngrade bool
+
+	// MaxSize the limit of the original in bytes, 0 means no limit.
+	MaxSize int64
+
+	// DialTimeout, TLSHandshakeTimeout and ResponseHeaderTimeout limit the phases of a request.
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	// Retries the number of retries of connection failures and 502, 503, 504 responses.
+	// The delay grows from RetryBackoff up to RetryMaxBackoff.
+	Retries         int
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+
+	// Credentials of the origin hosts.
+	Credentials []Credential
+
+	// Limiter limits the concurrency and the rate of the requests per host, nil disables it.
+	Limiter throttle.LimiterInterface
+
+	// Breaker fails fast the requests to the hosts that keep failing, nil disables it.
+	Breaker breaker.BreakerInterface
+
+	// HostLabels the host label of the metrics, nil reports every host as hostmatch.OtherLabel.
+	HostLabels hostmatch.LabelInterface
+}
+
 type HTTPTransport struct {
 	cache lru.CacheInterface
 	hash  hsum.HashInterface
 	fm    fs.FileInterface
-	inner http.Transport
+	inner *http.Transport
 	log   logger.LogInterface
+
+	credentials []credential
+
+	options Options
+
+	// jitter picks the delay before a retry up to the backoff.
+	jitter func(time.Duration) time.Duration
+
+	requests metrics.CounterInterface
+	errors   metrics.CounterInterface
+	latency  metrics.HistogramInterface
 }
 
 func New(
@@ -38,97 +107,228 @@ func New(
 	cache lru.CacheInterface,
 	fm fs.FileInterface,
 	log logger.LogInterface,
+	registry metrics.RegistryInterface,
+	options Options,
 ) *HTTPTransport {
 	return &HTTPTransport{
 		cache: cache,
 		hash:  hash,
 		fm:    fm,
-		inner: http.Transport{},
+		inner: newInner(options),
 		log:   log,
+
+		credentials: newCredentials(options),
+
+		options: options,
+		jitter:  fullJitter,
+
+		requests: registry.Counter("imgproxy_origin_requests_total",
+			"Requests to the origin servers.", "host", "code"),
+		errors: registry.Counter("imgproxy_origin_errors_total",
+			"Failed requests to the origin servers.", "host"),
+		latency: registry.Histogram("imgproxy_origin_request_duration_seconds",
+			"Latency of the requests to the origin servers.", metrics.DefBuckets, "host"),
 	}
 }
 
-func (t *HTTPTransport) roundTrip(req *http.Request) (*http.Response, error) {
-	resp, err := t.inner.RoundTrip(req)
-	if err != nil {
-		return nil, err
+func newInner(options Options) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   orDefault(options.DialTimeout, 30*time.Second),
+		KeepAlive: 30 * time.Second,
 	}
 
-	// redirect http to https
-	if req.URL.Scheme == "http" && resp.StatusCode >= 300 && resp.StatusCode <= 399 {
-		_ = resp.Body.Close()
+	if options.Guard != nil {
+		dialer.Control = options.Guard.Control
+	}
+
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   orDefault(options.TLSHandshakeTimeout, 10*time.Second),
+		ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
 
-		req.URL.Scheme = "https"
+// hostLabel the hosts come from the clients, so the label is one of the configured patterns.
+func (t *HTTPTransport) hostLabel(host string) string {
+	if t.options.HostLabels == nil {
+		return hostmatch.OtherLabel
+	}
+
+	return t.options.HostLabels.Label(host)
+}
 
-		return t.inner.RoundTrip(req)
+func orDefault(value, def time.Duration) time.Duration {
+	if value <= 0 {
+		return def
 	}
 
-	return resp, err
+	return value
 }
 
-func (t *HTTPTransport) createCache(req *http.Request) ([]byte, error) {
+// attempt a single request to the origin, the slot of the limiter is held until the body is closed.
+func (t *HTTPTransport) attempt(req *http.Request) (*http.Response, error) {
+	release := func() {}
+	if t.options.Limiter != nil {
+		var err error
+		if release, err = t.options.Limiter.Acquire(req.Context(), req.URL.Host); err != nil {
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, err)
+		}
+	}
+
+	inner, req := t.authorize(req)
+
 	now := time.Now()
-	resp, err := t.roundTrip(req)
+	resp, err := inner.RoundTrip(req)
 	latency := time.Since(now)
+
+	if err != nil {
+		release()
+	} else {
+		resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	}
+
+	host := t.hostLabel(req.URL.Host)
+
+	t.latency.Observe(latency.Seconds(), host)
 	if err != nil {
+		t.requests.Inc(host, "error")
+		t.errors.Inc(host)
+
 		return nil, err
 	}
 
+	t.requests.Inc(host, strconv.Itoa(resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK && !isRedirect(resp) {
+		t.errors.Inc(host)
+		t.logger(req).Warning("origin request failed",
+			"method", req.Method,
+			"url", req.URL.String(),
+			"status", resp.StatusCode,
+			"latency_us", latency.Microseconds(),
+			"user_agent", req.Header.Get("User-Agent"),
+		)
+	}
+
+	return resp, nil
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+
+	return b.ReadCloser.Close()
+}
+
+func (t *HTTPTransport) createCache(req *http.Request) (ResponseItem, []byte, error) {
+	resp, err := t.roundTrip(req)
+	if err != nil {
+		return ResponseItem{}, nil, err
+	}
+
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
-	if resp.StatusCode != 200 {
-		t.log.Warning(fmt.Sprintf(
-			"[%s] %s %s %s %d %s",
-			now.Format("02/Jan/2006:15:04:05 -0700"),
-			req.Method,
-			req.URL.String(),
-			resp.Status,
-			latency.Microseconds(),
-			req.Header.Get("User-Agent"),
-		))
-
-		return nil, ErrServerError
+	if resp.StatusCode != http.StatusOK {
+		return ResponseItem{}, nil, fmt.Errorf("%s: %w", resp.Status, ErrServerError)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := sizelimit.ReadAll(resp.Body, resp.ContentLength, t.options.MaxSize)
 	if err != nil {
-		return nil, err
+		return ResponseItem{}, nil, err
+	}
+
+	key, location := req.URL.String(), resp.Request.URL.String()
+	if location != key {
+		t.logger(req).Debug("original redirected", "url", key, "final_url", location)
+
+		// the content is shared by every url that redirects to the location
+		t.cache.Put(key, ResponseItem{URL: key, Location: location, size: uint64(len(location))})
+		key = location
 	}
 
-	if t.cache.Put(req.URL.String(), ResponseItem{
-		URL:  req.URL.String(),
-		size: uint64(resp.ContentLength),
-	}) {
-		err = t.fm.Create(t.hash.HashByString(req.URL.String()), body)
+	item := ResponseItem{
+		URL:         key,
+		ContentType: resp.Header.Get("Content-Type"),
+		size:        uint64(len(body)),
+	}
+
+	if t.cache.Put(key, item) {
+		err = t.fm.Create(t.hash.HashByString(key), body)
 		if err != nil {
-			return nil, err
+			return ResponseItem{}, nil, err
 		}
 	}
 
-	return body, nil
+	return item, body, nil
 }
 
 func (t *HTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if t.cache.Has(req.URL.String()) {
-		if body, err := t.fm.Content(t.hash.HashByString(req.URL.String())); err == nil {
-			return t.response(body, err)
-		}
+	if item, body, ok := t.cached(req.URL.String()); ok {
+		t.logger(req).Debug("original served from cache", "url", req.URL.String())
+
+		return t.response(item, body, nil)
 	}
 
 	return t.response(t.createCache(req))
 }
 
-func (t *HTTPTransport) response(body []byte, err error) (*http.Response, error) {
+// cached follows the link if the url was redirected.
+func (t *HTTPTransport) cached(key string) (ResponseItem, []byte, bool) {
+	val, ok := t.cache.Get(key)
+	if !ok {
+		return ResponseItem{}, nil, false
+	}
+
+	item, ok := val.(ResponseItem)
+	if !ok {
+		return ResponseItem{}, nil, false
+	}
+
+	if !item.HasContent() {
+		if val, ok = t.cache.Get(item.Location); !ok {
+			return ResponseItem{}, nil, false
+		}
+
+		if item, ok = val.(ResponseItem); !ok {
+			return ResponseItem{}, nil, false
+		}
+	}
+
+	body, err := t.fm.Content(t.hash.HashByString(item.URL))
+
+	return item, body, err == nil
+}
+
+func (t *HTTPTransport) logger(req *http.Request) logger.LogInterface {
+	return logger.FromContext(req.Context(), t.log)
+}
+
+// response the content type declared by the origin is kept, so a cached original is checked the same way.
+func (t *HTTPTransport) response(item ResponseItem, body []byte, err error) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
 
+	header := http.Header{}
+	if item.ContentType != "" {
+		header.Set("Content-Type", item.ContentType)
+	}
+
 	return &http.Response{
-		Header: http.Header{
-			"Content-Type": []string{http.DetectContentType(body)},
-		},
-		Body: io.NopCloser(bytes.NewReader(body)),
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Header:        header,
+		ContentLength: int64(len(body)),
+		Body:          io.NopCloser(bytes.NewReader(body)),
 	}, nil
 }
@@ -7,15 +7,20 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/rez1dent3/otus-final/internal/pkg/bus"
 	"github.com/rez1dent3/otus-final/internal/pkg/bytesize"
 	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/hostmatch"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
 	"github.com/rez1dent3/otus-final/internal/pkg/logger"
 	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/metrics"
+	"github.com/rez1dent3/otus-final/internal/pkg/netguard"
+	"github.com/rez1dent3/otus-final/internal/pkg/sizelimit"
 	"github.com/rez1dent3/otus-final/internal/transport"
 	"github.com/stretchr/testify/require"
 )
@@ -29,7 +34,7 @@ func newCache(hash hsum.HashInterface, fm fs.FileInterface) lru.CacheInterface {
 	cache := lru.New(bytesize.Parse("65K"), commandBus)
 
 	commandBus.Subscribe(lru.EventEvict, func(arg any) {
-		if data, ok := arg.(transport.ResponseItem); ok {
+		if data, ok := arg.(transport.ResponseItem); ok && data.HasContent() {
 			_ = fm.Delete(hash.HashByString(data.URL))
 		}
 	})
@@ -42,7 +47,7 @@ func newTransport(
 	fm fs.FileInterface,
 	cache lru.CacheInterface,
 ) *transport.HTTPTransport {
-	return transport.New(hash, cache, fm, logger.New("off", nil))
+	return transport.New(hash, cache, fm, logger.New("off", nil), metrics.New(), transport.Options{})
 }
 
 func TestHTTPTransport_RoundTrip(t *testing.T) {
@@ -107,3 +112,123 @@ func TestHTTPTransport_RoundTrip(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTPTransport_Guard(t *testing.T) {
+	hash := hsum.New()
+	fm := fs.New(os.TempDir(), "transport-test")
+	cache := newCache(hash, fm)
+
+	defer cache.Purge()
+
+	guard, err := netguard.New(nil, nil)
+	require.NoError(t, err)
+
+	httpTransport := transport.New(hash, cache, fm, logger.New("off", nil), metrics.New(), transport.Options{
+		Guard: guard,
+	})
+	client := http.Client{Transport: httpTransport, Timeout: time.Second}
+
+	server := fileServer()
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		server.URL+"/_gopher_original_1024x504.jpg", nil)
+	require.NoError(t, err)
+
+	response, err := client.Do(req) //nolint:bodyclose
+	require.ErrorIs(t, err, netguard.ErrBlockedAddress)
+	require.Nil(t, response)
+	require.False(t, cache.Has(req.URL.String()))
+}
+
+func TestHTTPTransport_MaxSize(t *testing.T) {
+	image, err := os.ReadFile("../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// flushing before the end drops the Content-Length
+			_, _ = w.Write(image[:1024])
+			w.(http.Flusher).Flush()
+			_, _ = w.Write(image[1024:])
+			return
+		}
+
+		_, _ = w.Write(image)
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/declared", "/chunked"} {
+		hash := hsum.New()
+		fm := fs.New(os.TempDir(), "transport-test")
+		cache := newCache(hash, fm)
+
+		httpTransport := transport.New(hash, cache, fm, logger.New("off", nil), metrics.New(), transport.Options{
+			MaxSize: 4096,
+		})
+
+		_, err := get(t, httpTransport, server.URL+path, http.Header{})
+		require.ErrorIs(t, err, sizelimit.ErrTooLarge, path)
+		require.False(t, cache.Has(server.URL+path))
+	}
+}
+
+func TestHTTPTransport_ContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeFile(w, r, "../../resources/images/_gopher_original_1024x504.jpg")
+	}))
+	defer server.Close()
+
+	httpTransport, cache := newRedirectTransport(t, transport.Options{})
+	client := http.Client{Transport: httpTransport, Timeout: time.Second}
+
+	// the declared type is the same for the miss and the hit, the content is checked by the fetcher
+	for _, fromCache := range []bool{false, true} {
+		require.Equal(t, fromCache, cache.Has(server.URL))
+
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestHTTPTransport_HostLabels(t *testing.T) {
+	hash := hsum.New()
+	fm := fs.New(os.TempDir(), "transport-test")
+	cache := newCache(hash, fm)
+
+	defer cache.Purge()
+
+	labels, err := hostmatch.NewLabels([]string{"127.0.0.1"})
+	require.NoError(t, err)
+
+	registry := metrics.New()
+	httpTransport := transport.New(hash, cache, fm, logger.New("off", nil), registry, transport.Options{
+		HostLabels: labels,
+	})
+
+	server := fileServer()
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	for _, host := range []string{serverURL.Host, "localhost:" + serverURL.Port()} {
+		_, err := get(t, httpTransport, "http://"+host+"/_gopher_original_1024x504.jpg", http.Header{})
+		require.NoError(t, err)
+	}
+
+	buffer := &strings.Builder{}
+	_, err = registry.WriteTo(buffer)
+	require.NoError(t, err)
+
+	require.Contains(t, buffer.String(), `imgproxy_origin_requests_total{host="127.0.0.1",code="200"} 1`)
+	require.Contains(t, buffer.String(), `imgproxy_origin_requests_total{host="other",code="200"} 1`)
+	require.NotContains(t, buffer.String(), "localhost")
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/rez1dent3/otus-final/internal/pkg/cachestat"
 	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
 	"github.com/rez1dent3/otus-final/internal/pkg/fs"
 	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/logger"
 	"github.com/rez1dent3/otus-final/internal/pkg/lru"
 	"github.com/rez1dent3/otus-final/internal/pkg/transformer"
 )
@@ -49,8 +51,21 @@ type impl struct {
 	transform transformer.TransformInterface
 }
 
-func (i *impl) cacheKey(originalURL string, width int, height int) string {
-	return i.hash.HashByString(fmt.Sprintf("fill:%s:%d:%d", originalURL, width, height))
+// cacheKey includes the validator of the original if the source has one, so a changed original gets
+// a new preview. The http sources report the query they pass to the origin as the validator.
+func (i *impl) cacheKey(ctx context.Context, originalURL string, width int, height int) (string, error) {
+	key := fmt.Sprintf("fill:%s:%d:%d", originalURL, width, height)
+
+	if validator, ok := i.fetch.(fetcher.ValidatorInterface); ok {
+		value, err := validator.Validator(ctx, originalURL)
+		if err != nil {
+			return "", err
+		}
+
+		key += ":" + value
+	}
+
+	return i.hash.HashByString(key), nil
 }
 
 func (i *impl) FillCenter(
@@ -60,13 +75,27 @@ func (i *impl) FillCenter(
 	height int,
 	header http.Header,
 ) ([]byte, error) {
-	cacheKey := i.cacheKey(originalURL, width, height)
+	log := logger.FromContext(ctx, logger.Nop())
+
+	cacheKey, err := i.cacheKey(ctx, originalURL, width, height)
+	if err != nil {
+		return nil, err
+	}
+
 	if _, ok := i.cache.Get(cacheKey); ok {
-		if body, err := i.fm.Content(cacheKey); err == nil {
+		body, err := i.fm.Content(cacheKey)
+		if err == nil {
+			cachestat.Mark(ctx, true)
+			log.Debug("preview served from cache", "key", cacheKey)
+
 			return body, nil
 		}
+
+		log.Warning("failed to read cached preview", "key", cacheKey, "error", err)
 	}
 
+	cachestat.Mark(ctx, false)
+
 	source, err := i.fetch.Get(ctx, originalURL, header)
 	if err != nil {
 		return nil, err
@@ -82,6 +111,8 @@ func (i *impl) FillCenter(
 		if err != nil {
 			return nil, err
 		}
+
+		log.Debug("preview cached", "key", cacheKey, "bytes", len(resp))
 	}
 
 	return resp, nil
@@ -0,0 +1,60 @@
+package usecases_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rez1dent3/otus-final/internal/pkg/bus"
+	"github.com/rez1dent3/otus-final/internal/pkg/fetcher"
+	"github.com/rez1dent3/otus-final/internal/pkg/fs"
+	"github.com/rez1dent3/otus-final/internal/pkg/hsum"
+	"github.com/rez1dent3/otus-final/internal/pkg/lru"
+	"github.com/rez1dent3/otus-final/internal/pkg/sniff"
+	"github.com/rez1dent3/otus-final/internal/usecases"
+	"github.com/stretchr/testify/require"
+)
+
+// transform counts the previews made.
+type transform struct {
+	calls int
+}
+
+func (t *transform) FillCenter(source []byte, _, _ int) ([]byte, error) {
+	t.calls++
+
+	return source[:16], nil
+}
+
+func (t *transform) IsSupported([]byte) bool {
+	return true
+}
+
+func TestFillCenter_LocalQuery(t *testing.T) {
+	root := t.TempDir()
+	body, err := os.ReadFile("../../resources/images/_gopher_original_1024x504.jpg")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "gopher.jpg"), body, 0o600))
+
+	fetch := fetcher.NewLocalFetcher("", map[string]string{"img": root}, sniff.New([]string{"image/jpeg"}, false), 0)
+	counter := &transform{}
+	useCase := usecases.New(
+		fs.New(t.TempDir(), "preview"),
+		hsum.New(),
+		lru.New(1<<20, bus.NewSyncBus()),
+		counter,
+		fetch,
+	)
+
+	// the local source does not read the query, so the requests share one preview
+	for _, query := range []string{"x=1", "x=2", ""} {
+		ctx := fetcher.WithQuery(context.Background(), query)
+
+		preview, err := useCase.FillCenter(ctx, "local/img/gopher.jpg", 100, 100, nil)
+		require.NoError(t, err, query)
+		require.Equal(t, body[:16], preview, query)
+	}
+
+	require.Equal(t, 1, counter.calls)
+}
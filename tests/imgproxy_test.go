@@ -164,3 +164,48 @@ func TestCheckSendingHeaders(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 	require.NoError(t, resp.Body.Close())
 }
+
+func TestCheckSourceQuery(t *testing.T) {
+	testCases := []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadGateway},
+		{"token=xyz", http.StatusBadGateway},
+		{"token=abc", http.StatusOK},
+		{"v=3&token=abc", http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", "http://imgproxy:8000", nil)
+		req.URL.Path = "/fill/640/480/nginx/signed/_gopher_original_1024x504.jpg"
+		req.URL.RawQuery = testCase.query
+
+		resp, _ := http.DefaultClient.Do(req)
+		require.NotNil(t, resp)
+		require.Equal(t, testCase.status, resp.StatusCode, testCase.query)
+		require.NoError(t, resp.Body.Close())
+	}
+}
+
+func TestCheckSourceScheme(t *testing.T) {
+	testCases := []struct {
+		url    string
+		status int
+	}{
+		// the scheme of the nginx host is http by the config
+		{"nginx/_gopher_original_1024x504.jpg", http.StatusOK},
+		{"http/nginx/_gopher_original_1024x504.jpg", http.StatusOK},
+		{"http://nginx/_gopher_original_1024x504.jpg", http.StatusOK},
+
+		// nginx does not serve https
+		{"https/nginx/_gopher_original_1024x504.jpg", http.StatusBadGateway},
+	}
+
+	for _, testCase := range testCases {
+		resp, _ := doRequest(testCase.url, 640, 480, nil)
+		require.NotNil(t, resp)
+		require.Equal(t, testCase.status, resp.StatusCode, testCase.url)
+		require.NoError(t, resp.Body.Close())
+	}
+}